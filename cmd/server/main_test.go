@@ -9,6 +9,9 @@ import (
 	"github.com/SamyRai/cityFinder/lib/city"
 	"github.com/SamyRai/cityFinder/lib/config"
 	"github.com/SamyRai/cityFinder/lib/finder"
+	"github.com/SamyRai/cityFinder/lib/finder/country"
+	"github.com/SamyRai/cityFinder/lib/finder/name"
+	"github.com/SamyRai/cityFinder/lib/finder/postalCode"
 	"github.com/SamyRai/cityFinder/lib/initializer"
 	"github.com/SamyRai/cityFinder/util"
 	"github.com/gofiber/fiber/v2"
@@ -30,8 +33,8 @@ import (
 
 type ServerTestSuite struct {
 	suite.Suite
-	app    *fiber.App
-	finder *finder.Finder
+	app     *fiber.App
+	finder  *finder.Finder
 	rootDir string
 }
 
@@ -49,6 +52,7 @@ func (suite *ServerTestSuite) SetupSuite() {
 	_ = os.Remove(filepath.Join(cfg.DatasetsFolder, cfg.S2.IndexFile))
 	_ = os.Remove(filepath.Join(cfg.DatasetsFolder, cfg.NameIndexFile))
 	_ = os.Remove(filepath.Join(cfg.DatasetsFolder, cfg.PostalCodeIndexFile))
+	_ = os.Remove(filepath.Join(cfg.DatasetsFolder, cfg.PostalCodeS2IndexFile))
 
 	suite.finder, err = initializer.Initialize(cfg)
 	require.NoError(suite.T(), err)
@@ -223,15 +227,571 @@ func (suite *ServerTestSuite) TestGetCityByPostalCodeRandom() {
 
 				assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
 
-				var cityObj city.City
-				err := json.NewDecoder(resp.Body).Decode(&cityObj)
+				var places []postalCode.Place
+				err := json.NewDecoder(resp.Body).Decode(&places)
 				assert.NoError(suite.T(), err)
-				assert.NotEmpty(suite.T(), cityObj.Name)
+				require.NotEmpty(suite.T(), places)
+				assert.NotEmpty(suite.T(), places[0].Name)
+				assert.Equal(suite.T(), code, places[0].PostalCode)
 			})
 		}
 	}
 }
 
+func (suite *ServerTestSuite) TestGetPlacesByPostalCode() {
+	req := httptest.NewRequest("GET", "/postalCode?code=AD500&country-code=ad", nil)
+	resp, _ := suite.app.Test(req, -1)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	var places []finder.PostalCodePlace
+	err := json.NewDecoder(resp.Body).Decode(&places)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), places, 1)
+	assert.Equal(suite.T(), "Andorra la Vella", places[0].Name)
+	assert.Equal(suite.T(), "AD500", places[0].PostalCode)
+	assert.Equal(suite.T(), "07", places[0].Admin1Code)
+	assert.Equal(suite.T(), 6, places[0].Accuracy)
+	require.NotNil(suite.T(), places[0].Region)
+	assert.Equal(suite.T(), "Andorra la Vella", places[0].Region.Name)
+
+	req = httptest.NewRequest("GET", "/postalCode?code=AD500&country-code=AD&minAccuracy=7", nil)
+	resp, _ = suite.app.Test(req, -1)
+	assert.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+
+	for _, code := range []string{"ad500", "AD 500", "500"} {
+		req = httptest.NewRequest("GET", "/postalCode?code="+url.QueryEscape(code)+"&country-code=AD", nil)
+		resp, _ = suite.app.Test(req, -1)
+		assert.Equal(suite.T(), http.StatusOK, resp.StatusCode, code)
+	}
+}
+
+func (suite *ServerTestSuite) TestPostalCodePlaceLinks() {
+	req := httptest.NewRequest("GET", "/postalCode?code=AD300&country-code=AD", nil)
+	resp, _ := suite.app.Test(req, -1)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	var places []finder.PostalCodePlace
+	err := json.NewDecoder(resp.Body).Decode(&places)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), places, 1)
+	assert.Equal(suite.T(), 3039678, places[0].GeonameID)
+	require.NotNil(suite.T(), places[0].Region)
+	assert.Equal(suite.T(), 3039676, places[0].Region.GeonameID)
+	assert.Equal(suite.T(), int64(3066), places[0].Population)
+	assert.Equal(suite.T(), "Europe/Andorra", places[0].Timezone)
+
+	req = httptest.NewRequest("GET", "/nearest?lat=42.55623&lon=1.53319", nil)
+	resp, _ = suite.app.Test(req, -1)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	var place finder.Place
+	err = json.NewDecoder(resp.Body).Decode(&place)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"AD300"}, place.PostalCodes)
+	require.NotNil(suite.T(), place.Region)
+	assert.Equal(suite.T(), "05", place.Region.Code)
+	assert.Equal(suite.T(), "Ordino", place.Region.Name)
+	assert.Equal(suite.T(), 3039676, place.Region.GeonameID)
+	assert.Nil(suite.T(), place.Subregion)
+
+	req = httptest.NewRequest("GET", "/coordinates?name=Ordino&country-code=AD&region=Ordino", nil)
+	resp, _ = suite.app.Test(req, -1)
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	req = httptest.NewRequest("GET", "/coordinates?name=Ordino&country-code=AD&subregion="+url.QueryEscape("Cook County"), nil)
+	resp, _ = suite.app.Test(req, -1)
+	assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode)
+}
+
+func (suite *ServerTestSuite) TestFullPlaceRecord() {
+	req := httptest.NewRequest("GET", "/nearest?lat=42.55623&lon=1.53319", nil)
+	resp, _ := suite.app.Test(req, -1)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	var place city.City
+	err := json.NewDecoder(resp.Body).Decode(&place)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 3039678, place.GeonameID)
+	assert.Equal(suite.T(), "Ordino", place.ASCIIName)
+	assert.Empty(suite.T(), place.CC2)
+	assert.Equal(suite.T(), "PPLA", place.FeatureCode)
+	assert.Equal(suite.T(), "05", place.Admin1Code)
+	assert.Equal(suite.T(), int64(3066), place.Population)
+	assert.Equal(suite.T(), 1296, place.DEM)
+	assert.Equal(suite.T(), "Europe/Andorra", place.Timezone)
+	assert.Equal(suite.T(), "2018-10-26", place.ModificationDate)
+}
+
+func (suite *ServerTestSuite) TestCountries() {
+	req := httptest.NewRequest("GET", "/countries?continent=eu", nil)
+	resp, _ := suite.app.Test(req, -1)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	var countries []country.Country
+	err := json.NewDecoder(resp.Body).Decode(&countries)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), countries, 4)
+	assert.Equal(suite.T(), "AD", countries[0].ISO)
+	assert.Equal(suite.T(), "GB", countries[2].ISO)
+
+	for _, code := range []string{"AD", "and", "020"} {
+		req = httptest.NewRequest("GET", "/countries/"+code, nil)
+		resp, _ = suite.app.Test(req, -1)
+		require.Equal(suite.T(), http.StatusOK, resp.StatusCode, code)
+
+		var andorra country.Country
+		err = json.NewDecoder(resp.Body).Decode(&andorra)
+		require.NoError(suite.T(), err)
+		assert.Equal(suite.T(), "Andorra la Vella", andorra.Capital)
+		assert.Equal(suite.T(), "EUR", andorra.CurrencyCode)
+		assert.Equal(suite.T(), []string{"ES", "FR"}, andorra.Neighbours)
+		assert.Equal(suite.T(), 3041565, andorra.GeonameID)
+	}
+
+	req = httptest.NewRequest("GET", "/countries/ZZ", nil)
+	resp, _ = suite.app.Test(req, -1)
+	assert.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+
+	req = httptest.NewRequest("GET", "/suggest?name=Ordino&country-code=zz", nil)
+	resp, _ = suite.app.Test(req, -1)
+	assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode)
+}
+
+func placeIDs(places []finder.Place) []int {
+	ids := make([]int, len(places))
+	for i, place := range places {
+		ids[i] = place.GeonameID
+	}
+	return ids
+}
+
+func (suite *ServerTestSuite) TestPlaceHierarchy() {
+	// The town of Ordino is not in the hierarchy table and is walked up through its admin codes
+	req := httptest.NewRequest("GET", "/place/3039678/ancestors", nil)
+	resp, _ := suite.app.Test(req, -1)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	var places []finder.Place
+	err := json.NewDecoder(resp.Body).Decode(&places)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []int{3039676, 3041565}, placeIDs(places))
+	assert.Equal(suite.T(), "PCLI", places[1].FeatureCode)
+
+	req = httptest.NewRequest("GET", "/place/3041565/children", nil)
+	resp, _ = suite.app.Test(req, -1)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	err = json.NewDecoder(resp.Body).Decode(&places)
+	require.NoError(suite.T(), err)
+	// The parishes of the hierarchy table come first, followed by the places whose admin codes lead to the country
+	ids := placeIDs(places)
+	require.Greater(suite.T(), len(ids), 2)
+	assert.Equal(suite.T(), []int{3039676, 3039162}, ids[:2])
+	assert.Contains(suite.T(), ids, 3023203)
+	assert.NotContains(suite.T(), ids[2:], 3039676)
+	assert.NotContains(suite.T(), ids, 3039678)
+
+	// Ordino is below its parish, which the hierarchy table lists no children for
+	req = httptest.NewRequest("GET", "/place/3039676/children", nil)
+	resp, _ = suite.app.Test(req, -1)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	err = json.NewDecoder(resp.Body).Decode(&places)
+	require.NoError(suite.T(), err)
+	assert.Contains(suite.T(), placeIDs(places), 3039678)
+
+	req = httptest.NewRequest("GET", "/place/3039678", nil)
+	resp, _ = suite.app.Test(req, -1)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	var place finder.Place
+	err = json.NewDecoder(resp.Body).Decode(&place)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "Ordino", place.Name)
+	assert.Equal(suite.T(), "PPLA", place.FeatureCode)
+	assert.Equal(suite.T(), []string{"AD300"}, place.PostalCodes)
+	require.NotNil(suite.T(), place.Region)
+	assert.Equal(suite.T(), 3039676, place.Region.GeonameID)
+
+	req = httptest.NewRequest("GET", "/place/1", nil)
+	resp, _ = suite.app.Test(req, -1)
+	assert.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+
+	req = httptest.NewRequest("GET", "/place/1/ancestors", nil)
+	resp, _ = suite.app.Test(req, -1)
+	assert.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+
+	req = httptest.NewRequest("GET", "/place/abc/children", nil)
+	resp, _ = suite.app.Test(req, -1)
+	assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode)
+}
+
+func (suite *ServerTestSuite) TestTimezone() {
+	cases := []struct {
+		location  string
+		zone      string
+		source    string
+		geonameID bool
+	}{
+		{"lat=42.55623&lon=1.53319", "Europe/Andorra", finder.TimezoneSourceBoundary, false},
+		{"lat=33.05&lon=-16.35", "Atlantic/Madeira", finder.TimezoneSourceBoundary, false},
+		{"lat=48.85&lon=2.35", "Europe/Andorra", finder.TimezoneSourcePlace, true},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("GET", "/timezone?"+tc.location, nil)
+		resp, _ := suite.app.Test(req, -1)
+		require.Equal(suite.T(), http.StatusOK, resp.StatusCode, tc.location)
+		var match finder.TimezoneMatch
+		err := json.NewDecoder(resp.Body).Decode(&match)
+		require.NoError(suite.T(), err)
+		assert.Equal(suite.T(), tc.zone, match.Zone, tc.location)
+		assert.Equal(suite.T(), tc.source, match.Source, tc.location)
+		assert.Equal(suite.T(), tc.geonameID, match.GeonameID != 0, tc.location)
+
+		location, err := time.LoadLocation(tc.zone)
+		require.NoError(suite.T(), err)
+		_, offset := match.LocalTime.Zone()
+		assert.Equal(suite.T(), match.OffsetSeconds, offset)
+		assert.Equal(suite.T(), match.DST, match.LocalTime.In(location).IsDST())
+		assert.WithinDuration(suite.T(), time.Now(), match.LocalTime, time.Minute)
+	}
+
+	for _, location := range []string{"lat=91&lon=0", "lat=42.55623", "lon=1.53319", "lat=42.55623,1.53319", "lat=42.55623,1.53319&lon=", "lat=&lon=1.53319"} {
+		req := httptest.NewRequest("GET", "/timezone?"+location, nil)
+		resp, _ := suite.app.Test(req, -1)
+		assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode, location)
+	}
+}
+
+func (suite *ServerTestSuite) TestFullPostalDataset() {
+	req := httptest.NewRequest("GET", "/postalCode?code=sw1a1aa&country-code=GB", nil)
+	resp, _ := suite.app.Test(req, -1)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	var places []postalCode.Place
+	err := json.NewDecoder(resp.Body).Decode(&places)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), places, 1)
+	assert.Equal(suite.T(), "SW1A 1AA", places[0].PostalCode)
+	assert.Equal(suite.T(), "E09000033", places[0].Admin3Code)
+
+	// Quotes are kept and the missing accuracy column is read as unknown
+	req = httptest.NewRequest("GET", "/postalCode?code=EC1A1BB&country-code=GB", nil)
+	resp, _ = suite.app.Test(req, -1)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	err = json.NewDecoder(resp.Body).Decode(&places)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), places, 1)
+	assert.Equal(suite.T(), `St Bartholomew's "Barts"`, places[0].Name)
+	assert.Zero(suite.T(), places[0].Accuracy)
+}
+
+func (suite *ServerTestSuite) TestPostalCodesByName() {
+	req := httptest.NewRequest("GET", "/postalCodes?name="+url.QueryEscape("al sabkha")+"&country-code=ae", nil)
+	resp, _ := suite.app.Test(req, -1)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	var places []finder.PostalCodePlace
+	err := json.NewDecoder(resp.Body).Decode(&places)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), places, 5)
+	assert.Equal(suite.T(), "28890 96004", places[0].PostalCode)
+	assert.Equal(suite.T(), "Al Sabkha", places[0].Name)
+
+	req = httptest.NewRequest("GET", "/postalCodes?name=Encamp", nil)
+	resp, _ = suite.app.Test(req, -1)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	err = json.NewDecoder(resp.Body).Decode(&places)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), places, 1)
+	assert.Equal(suite.T(), "AD200", places[0].PostalCode)
+	require.NotNil(suite.T(), places[0].Region)
+	assert.Equal(suite.T(), "Encamp", places[0].Region.Name)
+
+	req = httptest.NewRequest("GET", "/postalCodes?name=Encamp&region=04", nil)
+	resp, _ = suite.app.Test(req, -1)
+	assert.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+}
+
+func (suite *ServerTestSuite) TestNearestPostalCodes() {
+	req := httptest.NewRequest("GET", "/postalCode/nearest?lat=42.51&lon=1.51&limit=2", nil)
+	resp, _ := suite.app.Test(req, -1)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	var places []finder.NearbyPostalCode
+	err := json.NewDecoder(resp.Body).Decode(&places)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), places, 2)
+	assert.Equal(suite.T(), "AD500", places[0].PostalCode)
+	require.NotNil(suite.T(), places[0].Region)
+	assert.Equal(suite.T(), "07", places[0].Region.Code)
+	assert.Equal(suite.T(), 6, places[0].Accuracy)
+	assert.InDelta(suite.T(), 1.38, places[0].DistanceKm, 0.01)
+	assert.LessOrEqual(suite.T(), places[0].DistanceKm, places[1].DistanceKm)
+
+	for _, location := range []string{"lat=91&lon=1.51", "lat=42.51", "lat=42.51,1.51"} {
+		req = httptest.NewRequest("GET", "/postalCode/nearest?"+location, nil)
+		resp, _ = suite.app.Test(req, -1)
+		assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode, location)
+	}
+}
+
+func (suite *ServerTestSuite) TestPostalCodesAround() {
+	req := httptest.NewRequest("GET", "/postalCode/around?code=AD500&country-code=AD&radius=6", nil)
+	resp, _ := suite.app.Test(req, -1)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	var places []finder.NearbyPostalCode
+	err := json.NewDecoder(resp.Body).Decode(&places)
+	require.NoError(suite.T(), err)
+	codes := make([]string, len(places))
+	for i, place := range places {
+		codes[i] = place.PostalCode
+		assert.NotNil(suite.T(), place.Region, place.PostalCode)
+	}
+	assert.Equal(suite.T(), []string{"AD500", "AD600", "AD700"}, codes)
+	assert.Zero(suite.T(), places[0].DistanceKm)
+
+	req = httptest.NewRequest("GET", "/postalCode/around?code=AD999&country-code=AD&radius=6", nil)
+	resp, _ = suite.app.Test(req, -1)
+	assert.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+
+	req = httptest.NewRequest("GET", "/postalCode/around?code=AD500&country-code=AD&radius=0", nil)
+	resp, _ = suite.app.Test(req, -1)
+	assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode)
+}
+
+func (suite *ServerTestSuite) TestSearchPostalCodes() {
+	cases := map[string][]string{
+		"/postalCode/search?country-code=AD&prefix=AD1*":           {"AD100"},
+		"/postalCode/search?country-code=ad&prefix=AD*&limit=3":    {"AD100", "AD200", "AD300"},
+		"/postalCode/search?country-code=AD&range=AD200-AD400":     {"AD200", "AD300", "AD400"},
+		"/postalCode/search?country-code=AD&range=500-600":         {"AD500", "AD600"},
+		"/postalCode/search?country-code=AD&range=AD500..AD600":    {"AD500", "AD600"},
+		"/postalCode/search?country-code=PL&range=00-001..00-999":  {"00-001", "00-002", "00-950"},
+		"/postalCode/search?country-code=PL&from=00-002&to=01-001": {"00-002", "00-950", "01-001"},
+		"/postalCode/search?country-code=PL&prefix=00-9":           {"00-950"},
+	}
+	req := httptest.NewRequest("GET", "/postalCode/search?country-code=AD&prefix=AD300", nil)
+	resp, _ := suite.app.Test(req, -1)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	var linked postalCode.SearchResult
+	err := json.NewDecoder(resp.Body).Decode(&linked)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), linked.Codes, 1)
+	assert.Equal(suite.T(), []int{3039678}, linked.Codes[0].GeonameIDs)
+
+	for query, expected := range cases {
+		req := httptest.NewRequest("GET", query, nil)
+		resp, _ := suite.app.Test(req, -1)
+		require.Equal(suite.T(), http.StatusOK, resp.StatusCode, query)
+
+		var result postalCode.SearchResult
+		err := json.NewDecoder(resp.Body).Decode(&result)
+		require.NoError(suite.T(), err)
+		codes := make([]string, len(result.Codes))
+		for i, code := range result.Codes {
+			codes[i] = code.PostalCode
+			assert.NotEmpty(suite.T(), code.PlaceNames, query)
+		}
+		assert.Equal(suite.T(), expected, codes, query)
+	}
+
+	for _, query := range []string{
+		"/postalCode/search?country-code=AD",
+		"/postalCode/search?country-code=AD&prefix=AD*&range=AD100-AD200",
+		"/postalCode/search?country-code=AD&range=AD300-AD200",
+		"/postalCode/search?country-code=PL&range=00-001-00-999",
+		"/postalCode/search?country-code=PL&range=00-001..00-999&from=00-001",
+		"/postalCode/search?country-code=PL&from=00-001",
+	} {
+		req := httptest.NewRequest("GET", query, nil)
+		resp, _ := suite.app.Test(req, -1)
+		assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode, query)
+	}
+}
+
+func (suite *ServerTestSuite) TestValidatePostalCode() {
+	cases := []struct {
+		code               string
+		wellFormed, exists bool
+	}{
+		{"ad500", true, true},
+		{"AD800", true, false},
+		{"ADX00", false, false},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("GET", "/postalCode/validate?code="+url.QueryEscape(tc.code)+"&country-code=AD", nil)
+		resp, _ := suite.app.Test(req, -1)
+		require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+		var validation postalCode.Validation
+		err := json.NewDecoder(resp.Body).Decode(&validation)
+		require.NoError(suite.T(), err)
+		assert.Equal(suite.T(), tc.wellFormed, validation.WellFormed, tc.code)
+		assert.Equal(suite.T(), tc.exists, validation.Exists, tc.code)
+	}
+}
+
+func (suite *ServerTestSuite) TestGetCoordinatesByRegion() {
+	// "Camí Ral" exists both in Canillo (02) and in Encamp (03)
+	cases := map[string]float64{
+		"/coordinates?name=" + url.QueryEscape("Camí Ral, Encamp, AD"):                    42.53785,
+		"/coordinates?name=" + url.QueryEscape("Camí Ral") + "&country-code=AD&admin1=02": 42.54763,
+		"/coordinates?name=" + url.QueryEscape("Camí Ral, 03") + "&country-code=ad":       42.53785,
+	}
+	for query, latitude := range cases {
+		req := httptest.NewRequest("GET", query, nil)
+		resp, _ := suite.app.Test(req, -1)
+		require.Equal(suite.T(), http.StatusOK, resp.StatusCode, query)
+
+		var cityObj city.City
+		err := json.NewDecoder(resp.Body).Decode(&cityObj)
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), latitude, cityObj.Latitude, query)
+	}
+
+	// A region in the text that does not resolve is read as part of the name
+	req := httptest.NewRequest("GET", "/coordinates?name="+url.QueryEscape("Julià de Lòria, Sant")+"&country-code=AD", nil)
+	resp, _ := suite.app.Test(req, -1)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	var place finder.Place
+	err := json.NewDecoder(resp.Body).Decode(&place)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "Sant Julià de Lòria", place.Name)
+
+	req = httptest.NewRequest("GET", "/coordinates?name="+url.QueryEscape("Camí Ral, Atlantis, AD"), nil)
+	resp, _ = suite.app.Test(req, -1)
+	assert.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+
+	req = httptest.NewRequest("GET", "/coordinates?name="+url.QueryEscape("Camí Ral")+"&country-code=AD&region=Atlantis", nil)
+	resp, _ = suite.app.Test(req, -1)
+	assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode)
+}
+
+func (suite *ServerTestSuite) TestGetCoordinatesLocalized() {
+	cases := []struct {
+		query, acceptLanguage, expected string
+	}{
+		{"/coordinates?name=El+Tarter&country-code=AD", "", "El Tarter"},
+		{"/coordinates?name=El+Tarter&country-code=AD&lang=ru", "", "Эль-Тартер"},
+		{"/coordinates?name=Soldeu&country-code=AD", "de-CH,ru;q=0.9", "Soldeu"},
+		{"/coordinates?name=Soldeu&country-code=AD", "fr-FR,ru;q=0.9", "Солдеу"},
+		{"/coordinates?name=Soldeu&country-code=AD&lang=fr", "ru", "Soldeu"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("GET", tc.query, nil)
+		if tc.acceptLanguage != "" {
+			req.Header.Set("Accept-Language", tc.acceptLanguage)
+		}
+		resp, _ := suite.app.Test(req, -1)
+		require.Equal(suite.T(), http.StatusOK, resp.StatusCode, tc.query)
+
+		var cityObj city.City
+		err := json.NewDecoder(resp.Body).Decode(&cityObj)
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), tc.expected, cityObj.Name, tc.query)
+	}
+}
+
+func (suite *ServerTestSuite) TestSuggestions() {
+	// Three edits away from "Soldeu", too far for the fuzzy lookup
+	req := httptest.NewRequest("GET", "/coordinates?name=Solldeuxx&country-code=AD", nil)
+	resp, _ := suite.app.Test(req, -1)
+	require.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+
+	var notFound struct {
+		Error       string
+		Suggestions []name.Suggestion
+	}
+	err := json.NewDecoder(resp.Body).Decode(&notFound)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "City not found", notFound.Error)
+	require.NotEmpty(suite.T(), notFound.Suggestions)
+	assert.Equal(suite.T(), name.Suggestion{Name: "Soldeu", Country: "AD", Distance: 3, GeonameIDs: []int{3038999}}, notFound.Suggestions[0])
+
+	req = httptest.NewRequest("GET", "/suggest?name=Ordinu&limit=2", nil)
+	resp, _ = suite.app.Test(req, -1)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	var suggestions []name.Suggestion
+	err = json.NewDecoder(resp.Body).Decode(&suggestions)
+	require.NoError(suite.T(), err)
+	require.NotEmpty(suite.T(), suggestions)
+	assert.LessOrEqual(suite.T(), len(suggestions), 2)
+	assert.Equal(suite.T(), "Ordino", suggestions[0].Name)
+	assert.Equal(suite.T(), 1, suggestions[0].Distance)
+	assert.Contains(suite.T(), suggestions[0].GeonameIDs, 3039678)
+
+	req = httptest.NewRequest("GET", "/suggest?name=Ordinu&limit=500", nil)
+	resp, _ = suite.app.Test(req, -1)
+	assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode)
+}
+
+func (suite *ServerTestSuite) TestGeocode() {
+	cases := []struct {
+		text     string
+		expected finder.ParsedQuery
+		source   string
+		latitude float64
+	}{
+		{"Soldeu, AD", finder.ParsedQuery{Name: "Soldeu", CountryCode: "AD"}, finder.SourceName, 42.57688},
+		{"Ordino Andorra", finder.ParsedQuery{Name: "Ordino", CountryCode: "AD"}, finder.SourceName, 0},
+		{"Soldeu, Canillo", finder.ParsedQuery{Name: "Soldeu", Region: "Canillo", Admin1Code: "02", CountryCode: "AD"}, finder.SourceName, 42.57688},
+		{"Camí Ral, Encamp", finder.ParsedQuery{Name: "Camí Ral", Region: "Encamp", Admin1Code: "03", CountryCode: "AD"}, finder.SourceName, 42.53785},
+		{"Camí Ral, Canillo, Andorra", finder.ParsedQuery{Name: "Camí Ral", Region: "Canillo", Admin1Code: "02", CountryCode: "AD"}, finder.SourceName, 42.54763},
+		{"AD200 Vila", finder.ParsedQuery{Name: "Vila", CountryCode: "AD", PostalCode: "AD200"}, finder.SourceName, 42.53176},
+		{"AD500", finder.ParsedQuery{CountryCode: "AD", PostalCode: "AD500"}, finder.SourcePostalCode, 42.5},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("GET", "/geocode?q="+url.QueryEscape(tc.text), nil)
+		resp, _ := suite.app.Test(req, -1)
+		require.Equal(suite.T(), http.StatusOK, resp.StatusCode, tc.text)
+
+		var matches []finder.GeocodeMatch
+		err := json.NewDecoder(resp.Body).Decode(&matches)
+		require.NoError(suite.T(), err)
+		require.NotEmpty(suite.T(), matches, tc.text)
+		assert.Equal(suite.T(), tc.expected, matches[0].Query, tc.text)
+		assert.Equal(suite.T(), tc.source, matches[0].Source, tc.text)
+		if tc.latitude != 0 {
+			assert.Equal(suite.T(), tc.latitude, matches[0].Place.Latitude, tc.text)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/geocode?q="+url.QueryEscape("Atlantis, Nowhere"), nil)
+	resp, _ := suite.app.Test(req, -1)
+	assert.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+}
+
+func (suite *ServerTestSuite) TestSearchPattern() {
+	req := httptest.NewRequest("GET", "/search?pattern="+url.QueryEscape("sant *")+"&country-code=ad&feature-class=p", nil)
+	resp, _ := suite.app.Test(req, -1)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	var result finder.PatternResult
+	err := json.NewDecoder(resp.Body).Decode(&result)
+	require.NoError(suite.T(), err)
+	require.NotEmpty(suite.T(), result.Matches)
+	for _, match := range result.Matches {
+		assert.Equal(suite.T(), "P", match.City.FeatureClass)
+		if assert.NotNil(suite.T(), match.Region, match.MatchedName) {
+			assert.Equal(suite.T(), match.City.Admin1Code, match.Region.Code)
+		}
+		assert.True(suite.T(), strings.HasPrefix(strings.ToLower(match.MatchedName), "sant "), match.MatchedName)
+	}
+
+	req = httptest.NewRequest("GET", "/search?pattern="+url.QueryEscape("^Sant Rom")+"&regex=true&limit=2", nil)
+	resp, _ = suite.app.Test(req, -1)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	result = finder.PatternResult{}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	require.NoError(suite.T(), err)
+	assert.Len(suite.T(), result.Matches, 2)
+	assert.True(suite.T(), result.Truncated)
+
+	req = httptest.NewRequest("GET", "/search?pattern="+url.QueryEscape("Sant (")+"&regex=true", nil)
+	resp, _ = suite.app.Test(req, -1)
+	assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode)
+}
+
 func (suite *ServerTestSuite) TestBadRequest() {
 	req := httptest.NewRequest("GET", "/nearest?lat=invalid&lon=-74.0060", nil)
 	resp, _ := suite.app.Test(req, -1)
@@ -2,15 +2,64 @@
 package routes
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/SamyRai/cityFinder/lib/finder"
+	"github.com/SamyRai/cityFinder/lib/finder/name"
 	"github.com/gofiber/fiber/v2"
 	"log"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+)
+
+const (
+	defaultSuggestions = 5
+	maxSuggestions     = 50
+
+	defaultPatternMatches = 100
+	maxPatternMatches     = 1000
+	patternSearchTimeout  = 2 * time.Second
+
+	defaultGeocodeMatches = 10
+	maxGeocodeMatches     = 100
+
+	defaultPostalCodeMatches = 100
+	maxPostalCodeMatches     = 10000
+
+	maxNearestPostalCodes = 100
+	maxAroundRadiusKm     = 500.0
 )
 
 func SetupRoutes(app *fiber.App, mainFinder *finder.Finder) {
+	// Country codes are checked against the country table for every route that takes one
+	app.Use(func(c *fiber.Ctx) error {
+		if countryCode := c.Query("country-code"); countryCode != "" && !mainFinder.KnownCountry(strings.ToUpper(countryCode)) {
+			return c.Status(fiber.StatusBadRequest).SendString(fmt.Sprintf("Unknown country code: %s", countryCode))
+		}
+		return c.Next()
+	})
+
+	app.Get("/countries", func(c *fiber.Ctx) error {
+		if mainFinder.CountryFinder == nil {
+			return c.Status(fiber.StatusServiceUnavailable).SendString("Country table is not configured")
+		}
+		return c.JSON(mainFinder.CountryFinder.List(c.Query("continent")))
+	})
+
+	app.Get("/countries/:code", func(c *fiber.Ctx) error {
+		if mainFinder.CountryFinder == nil {
+			return c.Status(fiber.StatusServiceUnavailable).SendString("Country table is not configured")
+		}
+		country, ok := mainFinder.CountryFinder.Country(c.Params("code"))
+		if !ok {
+			return c.Status(fiber.StatusNotFound).SendString("Country not found")
+		}
+		return c.JSON(country)
+	})
+
 	app.Get("/nearest", func(c *fiber.Ctx) error {
 		lat, err := strconv.ParseFloat(c.Query("lat"), 64)
 		if err != nil {
@@ -38,25 +87,172 @@ func SetupRoutes(app *fiber.App, mainFinder *finder.Finder) {
 		if city == nil {
 			return c.Status(fiber.StatusNotFound).SendString(fmt.Sprintf("City not found for lat: %f, lon: %f", lat, lon))
 		}
-		return c.JSON(city)
+		return c.JSON(mainFinder.Describe(mainFinder.Localize(city, requestLanguages(c))))
 	})
 
 	app.Get("/coordinates", func(c *fiber.Ctx) error {
-		name := c.Query("name")
-		if name == "" {
+		cityName := c.Query("name")
+		if cityName == "" {
 			return c.Status(fiber.StatusBadRequest).SendString("Name is required")
 		}
-		countryCode := strings.ToUpper(c.Query("country-code"))
-		if countryCode == "" {
+		query, err := mainFinder.NameQuery(cityName, strings.ToUpper(c.Query("country-code")), c.Query("admin1", c.Query("region")), c.Query("subregion"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Unknown region")
+		}
+		if query.CountryCode == "" {
 			return c.Status(fiber.StatusBadRequest).SendString("Country code is required")
 		}
+		query.Match, err = name.ParseMatchMode(c.Query("match"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Match must be one of exact, fuzzy, phonetic or auto")
+		}
 
-		city := mainFinder.FindCityByName(name, countryCode)
-		if city == nil {
-			return c.Status(fiber.StatusNotFound).SendString("City not found")
+		if near := c.Query("near"); near != "" {
+			lat, lon, err := parseLatLon(near)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).SendString(fmt.Sprintf("Invalid near: %v", err))
+			}
+			query.Near = &name.Location{Latitude: lat, Longitude: lon}
+		}
+		if radius := c.Query("radius"); radius != "" {
+			if query.Near == nil {
+				return c.Status(fiber.StatusBadRequest).SendString("Radius requires near")
+			}
+			radiusKm, err := strconv.ParseFloat(radius, 64)
+			if err != nil || radiusKm <= 0 {
+				return c.Status(fiber.StatusBadRequest).SendString("Invalid radius")
+			}
+			query.RadiusKm = radiusKm
+		}
+
+		cities := mainFinder.SearchCitiesByName(query)
+		if len(cities) == 0 {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"Error":       "City not found",
+				"Suggestions": mainFinder.SuggestCityNames(query.Name, query.CountryCode, defaultSuggestions),
+			})
+		}
+
+		return c.JSON(mainFinder.Describe(mainFinder.Localize(cities[0], requestLanguages(c))))
+	})
+
+	app.Get("/place/:id", func(c *fiber.Ctx) error {
+		geonameID, err := c.ParamsInt("id")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Invalid geonameid")
+		}
+		place, err := mainFinder.PlaceByID(geonameID)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).SendString("Place not found")
+		}
+		place.City = *mainFinder.Localize(&place.City, requestLanguages(c))
+		return c.JSON(place)
+	})
+
+	app.Get("/place/:id/ancestors", func(c *fiber.Ctx) error {
+		geonameID, err := c.ParamsInt("id")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Invalid geonameid")
+		}
+		places, err := mainFinder.Ancestors(geonameID)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).SendString("Place not found")
+		}
+		return c.JSON(places)
+	})
+
+	app.Get("/place/:id/children", func(c *fiber.Ctx) error {
+		geonameID, err := c.ParamsInt("id")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Invalid geonameid")
+		}
+		places, err := mainFinder.Children(geonameID)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).SendString("Place not found")
+		}
+		return c.JSON(places)
+	})
+
+	app.Get("/suggest", func(c *fiber.Ctx) error {
+		cityName := c.Query("name")
+		if cityName == "" {
+			return c.Status(fiber.StatusBadRequest).SendString("Name is required")
+		}
+		limit := c.QueryInt("limit", defaultSuggestions)
+		if limit < 1 || limit > maxSuggestions {
+			return c.Status(fiber.StatusBadRequest).SendString(fmt.Sprintf("Limit must be between 1 and %d", maxSuggestions))
+		}
+
+		return c.JSON(mainFinder.SuggestCityNames(cityName, strings.ToUpper(c.Query("country-code")), limit))
+	})
+
+	app.Get("/geocode", func(c *fiber.Ctx) error {
+		text := c.Query("q")
+		if strings.TrimSpace(text) == "" {
+			return c.Status(fiber.StatusBadRequest).SendString("Query is required")
+		}
+		limit := c.QueryInt("limit", defaultGeocodeMatches)
+		if limit < 1 || limit > maxGeocodeMatches {
+			return c.Status(fiber.StatusBadRequest).SendString(fmt.Sprintf("Limit must be between 1 and %d", maxGeocodeMatches))
 		}
 
-		return c.JSON(city)
+		matches := mainFinder.Geocode(text)
+		if len(matches) == 0 {
+			return c.Status(fiber.StatusNotFound).SendString("Place not found")
+		}
+		if len(matches) > limit {
+			matches = matches[:limit]
+		}
+		languages := requestLanguages(c)
+		for i := range matches {
+			matches[i].Place = mainFinder.Localize(matches[i].Place, languages)
+		}
+
+		return c.JSON(matches)
+	})
+
+	app.Get("/search", func(c *fiber.Ctx) error {
+		query := name.PatternQuery{
+			Pattern:      c.Query("pattern"),
+			Regexp:       c.QueryBool("regex"),
+			CountryCode:  strings.ToUpper(c.Query("country-code")),
+			FeatureClass: strings.ToUpper(c.Query("feature-class")),
+			Limit:        c.QueryInt("limit", defaultPatternMatches),
+		}
+		if query.Pattern == "" {
+			return c.Status(fiber.StatusBadRequest).SendString("Pattern is required")
+		}
+		if query.Limit < 1 || query.Limit > maxPatternMatches {
+			return c.Status(fiber.StatusBadRequest).SendString(fmt.Sprintf("Limit must be between 1 and %d", maxPatternMatches))
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), patternSearchTimeout)
+		defer cancel()
+		result, err := mainFinder.SearchNamePatterns(ctx, query)
+		if errors.Is(err, context.DeadlineExceeded) {
+			return c.Status(fiber.StatusServiceUnavailable).SendString("Pattern search timed out, narrow it down by country or feature class")
+		}
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(fmt.Sprintf("Invalid pattern: %v", err))
+		}
+
+		return c.JSON(result)
+	})
+
+	app.Get("/timezone", func(c *fiber.Ctx) error {
+		lat, lon, err := queryLatLon(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(fmt.Sprintf("Invalid location: %v", err))
+		}
+
+		match, err := mainFinder.FindTimezone(lat, lon, time.Now())
+		if errors.Is(err, finder.ErrUnknownTimezone) {
+			return c.Status(fiber.StatusNotFound).SendString(fmt.Sprintf("Time zone not found for lat: %f, lon: %f", lat, lon))
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString(fmt.Sprintf("Error finding time zone: %v", err))
+		}
+		return c.JSON(match)
 	})
 
 	app.Get("/postalCode", func(c *fiber.Ctx) error {
@@ -68,11 +264,229 @@ func SetupRoutes(app *fiber.App, mainFinder *finder.Finder) {
 		if countryCode == "" {
 			return c.Status(fiber.StatusBadRequest).SendString("Country code is required")
 		}
-		city := mainFinder.FindCityByPostalCode(postalCode, countryCode)
-		if city == nil {
+		minAccuracy := c.QueryInt("minAccuracy", 0)
+		if minAccuracy < 0 {
+			return c.Status(fiber.StatusBadRequest).SendString("Minimum accuracy must not be negative")
+		}
+		places := mainFinder.FindCityByPostalCode(postalCode, countryCode, minAccuracy)
+		if len(places) == 0 {
 			return c.Status(fiber.StatusNotFound).SendString("City not found")
 		}
 
-		return c.JSON(city)
+		return c.JSON(places)
+	})
+
+	app.Get("/postalCodes", func(c *fiber.Ctx) error {
+		placeName := c.Query("name")
+		countryCode := strings.ToUpper(c.Query("country-code"))
+		if placeName == "" {
+			return c.Status(fiber.StatusBadRequest).SendString("Name is required")
+		}
+		minAccuracy := c.QueryInt("minAccuracy", 0)
+		if minAccuracy < 0 {
+			return c.Status(fiber.StatusBadRequest).SendString("Minimum accuracy must not be negative")
+		}
+		places := mainFinder.FindPostalCodesByName(placeName, countryCode, c.Query("region"), minAccuracy)
+		if len(places) == 0 {
+			return c.Status(fiber.StatusNotFound).SendString("Postal code not found")
+		}
+
+		return c.JSON(places)
+	})
+
+	app.Get("/postalCode/nearest", func(c *fiber.Ctx) error {
+		lat, lon, err := queryLatLon(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(fmt.Sprintf("Invalid location: %v", err))
+		}
+		limit := c.QueryInt("limit", 1)
+		if limit < 1 || limit > maxNearestPostalCodes {
+			return c.Status(fiber.StatusBadRequest).SendString(fmt.Sprintf("Limit must be between 1 and %d", maxNearestPostalCodes))
+		}
+		minAccuracy := c.QueryInt("minAccuracy", 0)
+		if minAccuracy < 0 {
+			return c.Status(fiber.StatusBadRequest).SendString("Minimum accuracy must not be negative")
+		}
+		if mainFinder.PostalCodeSpatialIndex == nil {
+			return c.Status(fiber.StatusServiceUnavailable).SendString("Postal code S2 index is not configured")
+		}
+
+		places, err := mainFinder.FindNearestPostalCodes(lat, lon, limit, minAccuracy)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString(fmt.Sprintf("Error finding postal code: %v", err))
+		}
+		if len(places) == 0 {
+			return c.Status(fiber.StatusNotFound).SendString(fmt.Sprintf("Postal code not found for lat: %f, lon: %f", lat, lon))
+		}
+		return c.JSON(places)
+	})
+
+	app.Get("/postalCode/around", func(c *fiber.Ctx) error {
+		postalCode := c.Query("code")
+		countryCode := strings.ToUpper(c.Query("country-code"))
+		if postalCode == "" {
+			return c.Status(fiber.StatusBadRequest).SendString("Postal code is required")
+		}
+		if countryCode == "" {
+			return c.Status(fiber.StatusBadRequest).SendString("Country code is required")
+		}
+		radius, err := strconv.ParseFloat(c.Query("radius"), 64)
+		if err != nil || radius <= 0 || radius > maxAroundRadiusKm {
+			return c.Status(fiber.StatusBadRequest).SendString(fmt.Sprintf("Radius must be a distance in km between 0 and %g", maxAroundRadiusKm))
+		}
+		minAccuracy := c.QueryInt("minAccuracy", 0)
+		if minAccuracy < 0 {
+			return c.Status(fiber.StatusBadRequest).SendString("Minimum accuracy must not be negative")
+		}
+		if mainFinder.PostalCodeSpatialIndex == nil {
+			return c.Status(fiber.StatusServiceUnavailable).SendString("Postal code S2 index is not configured")
+		}
+
+		places, err := mainFinder.FindPostalCodesAround(postalCode, countryCode, radius, minAccuracy)
+		if errors.Is(err, finder.ErrUnknownPostalCode) {
+			return c.Status(fiber.StatusNotFound).SendString("Postal code not found")
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString(fmt.Sprintf("Error finding postal codes: %v", err))
+		}
+		return c.JSON(places)
+	})
+
+	app.Get("/postalCode/search", func(c *fiber.Ctx) error {
+		countryCode := strings.ToUpper(c.Query("country-code"))
+		prefix, codeRange := c.Query("prefix"), c.Query("range")
+		from, to := c.Query("from"), c.Query("to")
+		if countryCode == "" {
+			return c.Status(fiber.StatusBadRequest).SendString("Country code is required")
+		}
+		searches := 0
+		for _, given := range []bool{prefix != "", codeRange != "", from != "" || to != ""} {
+			if given {
+				searches++
+			}
+		}
+		if searches != 1 {
+			return c.Status(fiber.StatusBadRequest).SendString("Either prefix, range or from and to is required")
+		}
+		limit := c.QueryInt("limit", defaultPostalCodeMatches)
+		if limit < 1 || limit > maxPostalCodeMatches {
+			return c.Status(fiber.StatusBadRequest).SendString(fmt.Sprintf("Limit must be between 1 and %d", maxPostalCodeMatches))
+		}
+
+		if prefix != "" {
+			return c.JSON(mainFinder.SearchPostalCodePrefix(prefix, countryCode, limit))
+		}
+		if codeRange != "" {
+			var err error
+			if from, to, err = parseCodeRange(codeRange); err != nil {
+				return c.Status(fiber.StatusBadRequest).SendString(fmt.Sprintf("Invalid range: %v", err))
+			}
+		}
+		result, err := mainFinder.SearchPostalCodeRange(from, to, countryCode, limit)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(fmt.Sprintf("Invalid range: %v", err))
+		}
+		return c.JSON(result)
 	})
+
+	app.Get("/postalCode/validate", func(c *fiber.Ctx) error {
+		postalCode := c.Query("code")
+		countryCode := strings.ToUpper(c.Query("country-code"))
+		if postalCode == "" {
+			return c.Status(fiber.StatusBadRequest).SendString("Postal code is required")
+		}
+		if countryCode == "" {
+			return c.Status(fiber.StatusBadRequest).SendString("Country code is required")
+		}
+
+		return c.JSON(mainFinder.ValidatePostalCode(postalCode, countryCode))
+	})
+}
+
+// parseLatLon parses a "lat,lon" pair and validates its range
+func parseLatLon(value string) (float64, float64, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("location must be in the form lat,lon")
+	}
+	return parseCoordinates(parts[0], parts[1])
+}
+
+// queryLatLon parses a point given as separate lat and lon query parameters, both of which are required
+func queryLatLon(c *fiber.Ctx) (float64, float64, error) {
+	if c.Query("lat") == "" {
+		return 0, 0, fmt.Errorf("latitude is required")
+	}
+	if c.Query("lon") == "" {
+		return 0, 0, fmt.Errorf("longitude is required")
+	}
+	return parseCoordinates(c.Query("lat"), c.Query("lon"))
+}
+
+// parseCoordinates parses a latitude and a longitude in decimal degrees and checks their ranges
+func parseCoordinates(latValue, lonValue string) (float64, float64, error) {
+	lat, err := strconv.ParseFloat(strings.TrimSpace(latValue), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid latitude")
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(lonValue), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid longitude")
+	}
+	if lat < -90 || lat > 90 {
+		return 0, 0, fmt.Errorf("latitude must be between -90 and 90")
+	}
+	if lon < -180 || lon > 180 {
+		return 0, 0, fmt.Errorf("longitude must be between -180 and 180")
+	}
+	return lat, lon, nil
+}
+
+// parseCodeRange splits a postal code range given as "from..to". The "from-to" form is accepted too as long as
+// the codes themselves contain no hyphen, which those of many countries do, e.g. "00-950" in PL.
+func parseCodeRange(value string) (string, string, error) {
+	if from, to, found := strings.Cut(value, ".."); found {
+		return from, to, nil
+	}
+	if strings.Count(value, "-") == 1 {
+		from, to, _ := strings.Cut(value, "-")
+		return from, to, nil
+	}
+	return "", "", fmt.Errorf("range must be in the form from..to")
+}
+
+// requestLanguages returns the languages the response should be localized in, most preferred first.
+// The lang query parameter takes precedence over the Accept-Language header.
+func requestLanguages(c *fiber.Ctx) []string {
+	if lang := c.Query("lang"); lang != "" {
+		return []string{lang}
+	}
+
+	type weighted struct {
+		language string
+		quality  float64
+	}
+	var languages []weighted
+	for _, part := range strings.Split(c.Get(fiber.HeaderAcceptLanguage), ",") {
+		language, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if language == "" || language == "*" {
+			continue
+		}
+		quality := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if q, err := strconv.ParseFloat(value, 64); err == nil {
+				quality = q
+			}
+		}
+		languages = append(languages, weighted{language, quality})
+	}
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+
+	result := make([]string, len(languages))
+	for i, l := range languages {
+		result[i] = l.language
+	}
+	return result
 }
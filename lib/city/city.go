@@ -4,12 +4,27 @@ import (
 	"math"
 )
 
+// City is a GeoNames place with the columns of its record in the allCountries dump
 type City struct {
-	Latitude  float64
-	Longitude float64
-	Name      string
-	Country   string
-	AltNames  []string
+	GeonameID        int
+	Latitude         float64
+	Longitude        float64
+	Name             string
+	ASCIIName        string // Name in plain ASCII characters
+	Country          string
+	CC2              []string // Alternate country codes
+	FeatureClass     string
+	FeatureCode      string
+	Admin1Code       string
+	Admin2Code       string
+	Admin3Code       string
+	Admin4Code       string
+	Population       int64
+	Elevation        int    // Elevation in meters, zero if unknown
+	DEM              int    // Digital elevation model in meters, -9999 for ocean areas
+	Timezone         string // IANA time zone ID, e.g. "Europe/Andorra"
+	ModificationDate string // Date of the last modification of the record, yyyy-MM-dd
+	AltNames         []string
 }
 
 type Rect struct {
@@ -9,16 +9,42 @@ import (
 )
 
 type Config struct {
-	DatasetsFolder      string `json:"datasets_folder"`
-	AllCitiesURL        string `json:"all_cities_url"`
-	PostalCodesURL      string `json:"postal_codes_url"`
-	AllCitiesFile       string `json:"all_cities_file"`
-	PostalCodesFile     string `json:"postal_codes_file"`
-	AllCitiesZip        string `json:"all_cities_zip"`
-	PostalCodesZip      string `json:"postal_codes_zip"`
-	NameIndexFile       string `json:"name_index_file"`
-	PostalCodeIndexFile string `json:"postal_code_index_file"`
-	S2                  S2     `json:"s2"`
+	DatasetsFolder        string          `json:"datasets_folder"`
+	AllCitiesURL          string          `json:"all_cities_url"`
+	PostalCodesURL        string          `json:"postal_codes_url"`
+	AllCitiesFile         string          `json:"all_cities_file"`
+	PostalCodesFile       string          `json:"postal_codes_file"`
+	AllCitiesZip          string          `json:"all_cities_zip"`
+	PostalCodesZip        string          `json:"postal_codes_zip"`
+	Admin1CodesURL        string          `json:"admin1_codes_url"`
+	Admin1CodesFile       string          `json:"admin1_codes_file"`
+	Admin2CodesURL        string          `json:"admin2_codes_url"`
+	Admin2CodesFile       string          `json:"admin2_codes_file"`
+	CountryInfoURL        string          `json:"country_info_url"`
+	CountryInfoFile       string          `json:"country_info_file"`
+	HierarchyURL          string          `json:"hierarchy_url"`
+	HierarchyZip          string          `json:"hierarchy_zip"`
+	HierarchyFile         string          `json:"hierarchy_file"`
+	TimezoneBoundaryFile  string          `json:"timezone_boundary_file"`
+	AlternateNamesURL     string          `json:"alternate_names_url"`
+	AlternateNamesZip     string          `json:"alternate_names_zip"`
+	AlternateNamesFile    string          `json:"alternate_names_file"`
+	Languages             []string        `json:"languages"`
+	NameIndexFile         string          `json:"name_index_file"`
+	PostalCodeIndexFile   string          `json:"postal_code_index_file"`
+	PostalCodeS2IndexFile string          `json:"postal_code_s2_index_file"`
+	PostalDatasets        []PostalDataset `json:"postal_datasets"`
+	FuzzyEngine           string          `json:"fuzzy_engine"`
+	S2                    S2              `json:"s2"`
+}
+
+// PostalDataset is an additional GeoNames postal code file, such as the full-resolution GB_full, NL_full or CA_full.
+// Datasets are merged after the main postal code file in the order they are listed.
+type PostalDataset struct {
+	URL     string `json:"url"`
+	Zip     string `json:"zip"`
+	File    string `json:"file"`
+	Replace bool   `json:"replace"` // Drop the earlier entries of every country the dataset covers instead of merging code by code
 }
 
 type S2 struct {
@@ -64,4 +90,3 @@ func LoadConfig(configPath string) (*Config, error) {
 
 	return nil, fmt.Errorf("no config file provided")
 }
-
@@ -0,0 +1,58 @@
+package dataLoader
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// AdminCode is a row of the GeoNames admin code tables (admin1CodesASCII.txt and admin2Codes.txt).
+// The code of a second-order division includes its first-order one, e.g. "08.081".
+type AdminCode struct {
+	CountryCode string
+	Code        string
+	Name        string
+	ASCIIName   string
+	GeonameID   int
+}
+
+// LoadAdminCodes reads a GeoNames admin code table where each key has the form "CC.CODE"
+func LoadAdminCodes(filepath string) ([]AdminCode, error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+
+	scanner := bufio.NewScanner(file)
+	var codes []AdminCode
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 4 {
+			continue
+		}
+		countryCode, code, found := strings.Cut(fields[0], ".")
+		if !found {
+			continue
+		}
+		geonameID, _ := strconv.Atoi(fields[3])
+		codes = append(codes, AdminCode{
+			CountryCode: countryCode,
+			Code:        code,
+			Name:        fields[1],
+			ASCIIName:   fields[2],
+			GeonameID:   geonameID,
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to scan file: %v, %v", filepath, err)
+	}
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close file: %v", err)
+	}
+
+	return codes, nil
+}
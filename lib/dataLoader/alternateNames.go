@@ -0,0 +1,84 @@
+package dataLoader
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// AlternateName is a row of the GeoNames alternateNamesV2.txt table
+type AlternateName struct {
+	GeonameID  int
+	Language   string
+	Name       string
+	Preferred  bool
+	Short      bool
+	Colloquial bool
+	Historic   bool
+}
+
+// nonLanguageCodes are the pseudo language codes GeoNames uses for links, airport codes and the like
+var nonLanguageCodes = map[string]bool{
+	"link": true, "wkdt": true, "post": true, "iata": true, "icao": true, "faac": true,
+	"abbr": true, "unlc": true, "tcid": true, "phon": true, "piny": true, "fr_1793": true,
+}
+
+// LoadAlternateNames reads the language-tagged alternate names, keeping only the given languages if any are listed
+func LoadAlternateNames(filepath string, languages []string) ([]AlternateName, error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+
+	wanted := make(map[string]bool, len(languages))
+	for _, language := range languages {
+		wanted[strings.ToLower(language)] = true
+	}
+
+	scanner := bufio.NewScanner(file)
+	var names []AlternateName
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 8 {
+			continue
+		}
+		language := strings.ToLower(fields[2])
+		if language == "" || nonLanguageCodes[language] {
+			continue
+		}
+		if len(wanted) > 0 && !wanted[language] && !wanted[baseLanguage(language)] {
+			continue
+		}
+		geonameID, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		names = append(names, AlternateName{
+			GeonameID:  geonameID,
+			Language:   language,
+			Name:       fields[3],
+			Preferred:  fields[4] == "1",
+			Short:      fields[5] == "1",
+			Colloquial: fields[6] == "1",
+			Historic:   fields[7] == "1",
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to scan file: %v, %v", filepath, err)
+	}
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close file: %v", err)
+	}
+
+	return names, nil
+}
+
+// baseLanguage strips the region from a language tag, e.g. "zh-CN" becomes "zh"
+func baseLanguage(language string) string {
+	base, _, _ := strings.Cut(language, "-")
+	return base
+}
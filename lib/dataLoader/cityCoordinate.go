@@ -26,6 +26,11 @@ func LoadGeoNamesCSV(filepath string) ([]city.SpatialCity, error) {
 			continue
 		}
 
+		geonameID, err := strconv.Atoi(fields[0])
+		if err != nil {
+			log.Printf("Error parsing geonameid: %v on line: %s\n", err, line)
+			continue
+		}
 		lat, err := strconv.ParseFloat(fields[4], 64)
 		if err != nil {
 			log.Printf("Error parsing lat: %v on line: %s\n", err, line)
@@ -38,13 +43,30 @@ func LoadGeoNamesCSV(filepath string) ([]city.SpatialCity, error) {
 		}
 
 		altNames := strings.Split(fields[3], ",")
+		population, _ := strconv.ParseInt(fields[14], 10, 64)
+		elevation, _ := strconv.Atoi(fields[15])
+		dem, _ := strconv.Atoi(fields[16])
 
 		cityObj := city.City{
-			Latitude:  lat,
-			Longitude: lon,
-			Name:      fields[1],
-			Country:   fields[8],
-			AltNames:  altNames,
+			GeonameID:        geonameID,
+			Latitude:         lat,
+			Longitude:        lon,
+			Name:             fields[1],
+			ASCIIName:        fields[2],
+			Country:          fields[8],
+			CC2:              splitList(fields[9]),
+			FeatureClass:     fields[6],
+			FeatureCode:      fields[7],
+			Admin1Code:       fields[10],
+			Admin2Code:       fields[11],
+			Admin3Code:       fields[12],
+			Admin4Code:       fields[13],
+			Population:       population,
+			Elevation:        elevation,
+			DEM:              dem,
+			Timezone:         fields[17],
+			ModificationDate: fields[18],
+			AltNames:         altNames,
 		}
 
 		rect := &city.Rect{
@@ -94,11 +116,30 @@ func StreamGeoNamesCSV(filepath string, cityChan chan<- city.SpatialCity, errCha
 			continue
 		}
 
+		geonameID, _ := strconv.Atoi(fields[0])
 		cityObj := city.City{
-			Latitude:  lat,
-			Longitude: lon,
-			Name:      fields[1],
-			Country:   fields[8],
+			GeonameID:    geonameID,
+			Latitude:     lat,
+			Longitude:    lon,
+			Name:         fields[1],
+			Country:      fields[8],
+			FeatureClass: fields[6],
+			FeatureCode:  fields[7],
+		}
+		if len(fields) > 11 {
+			cityObj.Admin1Code = fields[10]
+			cityObj.Admin2Code = fields[11]
+		}
+		if len(fields) > 18 {
+			cityObj.ASCIIName = fields[2]
+			cityObj.CC2 = splitList(fields[9])
+			cityObj.Admin3Code = fields[12]
+			cityObj.Admin4Code = fields[13]
+			cityObj.Population, _ = strconv.ParseInt(fields[14], 10, 64)
+			cityObj.Elevation, _ = strconv.Atoi(fields[15])
+			cityObj.DEM, _ = strconv.Atoi(fields[16])
+			cityObj.Timezone = fields[17]
+			cityObj.ModificationDate = fields[18]
 		}
 
 		rect := &city.Rect{
@@ -120,3 +161,11 @@ func StreamGeoNamesCSV(filepath string, cityChan chan<- city.SpatialCity, errCha
 	close(cityChan)
 	close(errChan)
 }
+
+// splitList splits a comma-separated column, an empty column yields no values
+func splitList(field string) []string {
+	if field == "" {
+		return nil
+	}
+	return strings.Split(field, ",")
+}
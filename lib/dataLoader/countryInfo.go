@@ -0,0 +1,85 @@
+package dataLoader
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// CountryInfo is a row of the GeoNames country table (countryInfo.txt)
+type CountryInfo struct {
+	ISO              string
+	ISO3             string
+	ISONumeric       string
+	FIPS             string
+	Name             string
+	Capital          string
+	AreaKm2          float64
+	Population       int64
+	Continent        string
+	TLD              string
+	CurrencyCode     string
+	CurrencyName     string
+	Phone            string
+	PostalCodeFormat string
+	PostalCodeRegex  string
+	Languages        []string
+	GeonameID        int
+	Neighbours       []string
+}
+
+// LoadCountryInfo reads the GeoNames country table, skipping its comment lines
+func LoadCountryInfo(filepath string) ([]CountryInfo, error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+
+	scanner := bufio.NewScanner(file)
+	var countries []CountryInfo
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 18 || fields[0] == "" {
+			continue
+		}
+		area, _ := strconv.ParseFloat(fields[6], 64)
+		population, _ := strconv.ParseInt(fields[7], 10, 64)
+		geonameID, _ := strconv.Atoi(fields[16])
+		countries = append(countries, CountryInfo{
+			ISO:              fields[0],
+			ISO3:             fields[1],
+			ISONumeric:       fields[2],
+			FIPS:             fields[3],
+			Name:             fields[4],
+			Capital:          fields[5],
+			AreaKm2:          area,
+			Population:       population,
+			Continent:        fields[8],
+			TLD:              fields[9],
+			CurrencyCode:     fields[10],
+			CurrencyName:     fields[11],
+			Phone:            fields[12],
+			PostalCodeFormat: fields[13],
+			PostalCodeRegex:  fields[14],
+			Languages:        splitList(fields[15]),
+			GeonameID:        geonameID,
+			Neighbours:       splitList(fields[17]),
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to scan file: %v, %v", filepath, err)
+	}
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close file: %v", err)
+	}
+
+	return countries, nil
+}
@@ -0,0 +1,56 @@
+package dataLoader
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// HierarchyLink is a row of the GeoNames hierarchy table (hierarchy.txt)
+type HierarchyLink struct {
+	ParentID int
+	ChildID  int
+	Type     string // "ADM" for the administrative hierarchy, other values for user-defined ones
+}
+
+// LoadHierarchy reads the GeoNames parent-child table
+func LoadHierarchy(filepath string) ([]HierarchyLink, error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+
+	scanner := bufio.NewScanner(file)
+	var links []HierarchyLink
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 2 {
+			continue
+		}
+		parentID, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		childID, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		link := HierarchyLink{ParentID: parentID, ChildID: childID}
+		if len(fields) > 2 {
+			link.Type = fields[2]
+		}
+		links = append(links, link)
+	}
+
+	if err := scanner.Err(); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to scan file: %v, %v", filepath, err)
+	}
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close file: %v", err)
+	}
+
+	return links, nil
+}
@@ -0,0 +1,68 @@
+package dataLoader
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// TimezoneBoundary is the area of an IANA time zone, as published by timezone-boundary-builder
+type TimezoneBoundary struct {
+	TZID     string
+	Polygons [][][][2]float64 // Polygons of rings of [longitude, latitude] points, the first ring of each being its shell
+}
+
+type geoJSONFeatureCollection struct {
+	Features []struct {
+		Properties struct {
+			TZID string `json:"tzid"`
+		} `json:"properties"`
+		Geometry struct {
+			Type        string          `json:"type"`
+			Coordinates json.RawMessage `json:"coordinates"`
+		} `json:"geometry"`
+	} `json:"features"`
+}
+
+// LoadTimezoneBoundaries reads a GeoJSON feature collection of Polygon and MultiPolygon features
+// with the IANA zone ID in their tzid property. Other geometries are skipped.
+func LoadTimezoneBoundaries(filepath string) ([]TimezoneBoundary, error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+
+	var collection geoJSONFeatureCollection
+	decodeErr := json.NewDecoder(file).Decode(&collection)
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close file: %v", err)
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("failed to decode file: %v, %v", filepath, decodeErr)
+	}
+
+	boundaries := make([]TimezoneBoundary, 0, len(collection.Features))
+	for _, feature := range collection.Features {
+		if feature.Properties.TZID == "" {
+			continue
+		}
+		boundary := TimezoneBoundary{TZID: feature.Properties.TZID}
+		switch feature.Geometry.Type {
+		case "Polygon":
+			var polygon [][][2]float64
+			if err := json.Unmarshal(feature.Geometry.Coordinates, &polygon); err != nil {
+				return nil, fmt.Errorf("failed to decode polygon of %s: %v", boundary.TZID, err)
+			}
+			boundary.Polygons = [][][][2]float64{polygon}
+		case "MultiPolygon":
+			if err := json.Unmarshal(feature.Geometry.Coordinates, &boundary.Polygons); err != nil {
+				return nil, fmt.Errorf("failed to decode multipolygon of %s: %v", boundary.TZID, err)
+			}
+		default:
+			continue
+		}
+		boundaries = append(boundaries, boundary)
+	}
+
+	return boundaries, nil
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/csv"
 	"os"
 	"strconv"
+	"strings"
 )
 
 type PostalCodeEntry struct {
@@ -19,15 +20,24 @@ type PostalCodeEntry struct {
 	Latitude    float64
 	Longitude   float64
 	Accuracy    int
+	GeonameID   int    // Populated place the entry is linked to when the index is built, zero if none matched
+	Population  int64  // Population of the linked place
+	Timezone    string // Time zone of the linked place
 }
 
-func LoadPostalCodes(filepath string) (map[string]map[string]PostalCodeEntry, error) {
+// LoadPostalCodes reads a GeoNames postal code dump into a map of country code to postal code to entries.
+// A postal code often covers several places, so all of its entries are kept in file order.
+// Parsing is lenient, as the full-resolution files differ from the main dump: quotes are taken literally,
+// missing trailing columns are left empty and rows without a country or a postal code are skipped.
+func LoadPostalCodes(filepath string) (map[string]map[string][]PostalCodeEntry, error) {
 	file, err := os.Open(filepath)
 	if err != nil {
 		return nil, err
 	}
 	reader := csv.NewReader(file)
 	reader.Comma = '\t'
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
 	records, err := reader.ReadAll()
 	if err != nil {
 		_ = file.Close()
@@ -37,31 +47,53 @@ func LoadPostalCodes(filepath string) (map[string]map[string]PostalCodeEntry, er
 		return nil, err
 	}
 
-	postalCodes := make(map[string]map[string]PostalCodeEntry)
+	postalCodes := make(map[string]map[string][]PostalCodeEntry)
 	for _, record := range records {
-		lat, _ := strconv.ParseFloat(record[9], 64)
-		lon, _ := strconv.ParseFloat(record[10], 64)
-		accuracy, _ := strconv.Atoi(record[11])
+		field := func(i int) string {
+			if i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		if field(0) == "" || field(1) == "" {
+			continue
+		}
+		lat, _ := strconv.ParseFloat(field(9), 64)
+		lon, _ := strconv.ParseFloat(field(10), 64)
+		accuracy, _ := strconv.Atoi(field(11))
 		postalCode := PostalCodeEntry{
-			CountryCode: record[0],
-			PostalCode:  record[1],
-			PlaceName:   record[2],
-			AdminName1:  record[3],
-			AdminCode1:  record[4],
-			AdminName2:  record[5],
-			AdminCode2:  record[6],
-			AdminName3:  record[7],
-			AdminCode3:  record[8],
+			CountryCode: field(0),
+			PostalCode:  field(1),
+			PlaceName:   field(2),
+			AdminName1:  field(3),
+			AdminCode1:  field(4),
+			AdminName2:  field(5),
+			AdminCode2:  field(6),
+			AdminName3:  field(7),
+			AdminCode3:  field(8),
 			Latitude:    lat,
 			Longitude:   lon,
 			Accuracy:    accuracy,
 		}
 
 		if _, exists := postalCodes[postalCode.CountryCode]; !exists {
-			postalCodes[postalCode.CountryCode] = make(map[string]PostalCodeEntry)
+			postalCodes[postalCode.CountryCode] = make(map[string][]PostalCodeEntry)
 		}
-		postalCodes[postalCode.CountryCode][postalCode.PostalCode] = postalCode
+		postalCodes[postalCode.CountryCode][postalCode.PostalCode] = append(postalCodes[postalCode.CountryCode][postalCode.PostalCode], postalCode)
 	}
 
 	return postalCodes, nil
 }
+
+// MergePostalCodes adds the entries of a later dataset to earlier ones. Its entries take precedence: they replace
+// the earlier entries of the same postal code or, with replace set, every earlier entry of the countries it covers.
+func MergePostalCodes(postalCodes, later map[string]map[string][]PostalCodeEntry, replace bool) {
+	for countryCode, codes := range later {
+		if _, exists := postalCodes[countryCode]; !exists || replace {
+			postalCodes[countryCode] = make(map[string][]PostalCodeEntry, len(codes))
+		}
+		for code, entries := range codes {
+			postalCodes[countryCode][code] = entries
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package admin
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/SamyRai/cityFinder/lib/dataLoader"
+)
+
+// Division is an administrative division such as a state, a province or a Land, or one of their counties or districts
+type Division struct {
+	CountryCode string
+	Admin1Code  string // First-order division a second-order one belongs to, empty for first-order divisions
+	Code        string
+	Name        string
+	ASCIIName   string
+	GeonameID   int
+}
+
+// Finder resolves administrative divisions by code or by name
+type Finder struct {
+	Divisions    map[string]map[string]*Division // Map of country code to admin code to division
+	Subdivisions map[string]map[string]*Division // Map of country code to "admin1.admin2" code to second-order division
+	names        map[string]map[string]string    // Map of country code to lower-cased name to admin code
+	subnames     map[string]map[string][]string  // Map of country code to lower-cased name to "admin1.admin2" codes
+	mutex        sync.RWMutex                    // Mutex for thread-safe operations
+}
+
+// NewAdminFinder creates a new Finder instance
+func NewAdminFinder() *Finder {
+	return &Finder{
+		Divisions:    make(map[string]map[string]*Division),
+		Subdivisions: make(map[string]map[string]*Division),
+		names:        make(map[string]map[string]string),
+		subnames:     make(map[string]map[string][]string),
+	}
+}
+
+// BuildIndex creates an admin division index from the GeoNames admin1 and admin2 code tables
+func BuildIndex(admin1Codes, admin2Codes []dataLoader.AdminCode) *Finder {
+	finder := NewAdminFinder()
+	for _, code := range admin1Codes {
+		finder.AddDivision(Division{CountryCode: code.CountryCode, Code: code.Code, Name: code.Name, ASCIIName: code.ASCIIName, GeonameID: code.GeonameID})
+	}
+	for _, code := range admin2Codes {
+		admin1Code, admin2Code, found := strings.Cut(code.Code, ".")
+		if !found {
+			continue
+		}
+		finder.AddSubdivision(Division{CountryCode: code.CountryCode, Admin1Code: admin1Code, Code: admin2Code, Name: code.Name, ASCIIName: code.ASCIIName, GeonameID: code.GeonameID})
+	}
+	return finder
+}
+
+// AddDivision adds a division to the Finder
+func (af *Finder) AddDivision(division Division) {
+	af.mutex.Lock()
+	defer af.mutex.Unlock()
+
+	if _, exists := af.Divisions[division.CountryCode]; !exists {
+		af.Divisions[division.CountryCode] = make(map[string]*Division)
+		af.names[division.CountryCode] = make(map[string]string)
+	}
+	af.Divisions[division.CountryCode][division.Code] = &division
+	af.names[division.CountryCode][strings.ToLower(division.Name)] = division.Code
+	af.names[division.CountryCode][strings.ToLower(division.ASCIIName)] = division.Code
+}
+
+// AddSubdivision adds a second-order division to the Finder
+func (af *Finder) AddSubdivision(division Division) {
+	af.mutex.Lock()
+	defer af.mutex.Unlock()
+
+	if _, exists := af.Subdivisions[division.CountryCode]; !exists {
+		af.Subdivisions[division.CountryCode] = make(map[string]*Division)
+		af.subnames[division.CountryCode] = make(map[string][]string)
+	}
+	key := division.Admin1Code + "." + division.Code
+	af.Subdivisions[division.CountryCode][key] = &division
+	for _, name := range []string{strings.ToLower(division.Name), strings.ToLower(division.ASCIIName)} {
+		if codes := af.subnames[division.CountryCode][name]; len(codes) == 0 || codes[len(codes)-1] != key {
+			af.subnames[division.CountryCode][name] = append(codes, key)
+		}
+	}
+}
+
+// Subdivision returns the second-order division with the given admin codes, or nil if it is unknown
+func (af *Finder) Subdivision(countryCode, admin1Code, admin2Code string) *Division {
+	af.mutex.RLock()
+	defer af.mutex.RUnlock()
+
+	return af.Subdivisions[countryCode][admin1Code+"."+admin2Code]
+}
+
+// ResolveSubregion turns a second-order division given as an admin2 code or as a name into its admin1 and admin2 codes.
+// An admin1 code restricts the search to that division; without it a name or code shared by divisions of several
+// first-order divisions is ambiguous and not resolved.
+func (af *Finder) ResolveSubregion(countryCode, admin1Code, subregion string) (string, string, bool) {
+	af.mutex.RLock()
+	defer af.mutex.RUnlock()
+
+	subregion = strings.TrimSpace(subregion)
+	var matches []*Division
+	for _, key := range af.subnames[countryCode][strings.ToLower(subregion)] {
+		matches = append(matches, af.Subdivisions[countryCode][key])
+	}
+	if len(matches) == 0 {
+		for _, division := range af.Subdivisions[countryCode] {
+			if strings.EqualFold(division.Code, subregion) {
+				matches = append(matches, division)
+			}
+		}
+	}
+
+	var found *Division
+	for _, division := range matches {
+		if admin1Code != "" && division.Admin1Code != admin1Code {
+			continue
+		}
+		if found != nil && found != division {
+			return "", "", false
+		}
+		found = division
+	}
+	if found == nil {
+		return "", "", false
+	}
+	return found.Admin1Code, found.Code, true
+}
+
+// Division returns the division with the given admin code, or nil if it is unknown
+func (af *Finder) Division(countryCode, code string) *Division {
+	af.mutex.RLock()
+	defer af.mutex.RUnlock()
+
+	return af.Divisions[countryCode][code]
+}
+
+// Lookup returns the divisions of any country whose admin code or name is the given region, ordered by country
+func (af *Finder) Lookup(region string) []*Division {
+	af.mutex.RLock()
+	defer af.mutex.RUnlock()
+
+	region = strings.TrimSpace(region)
+	var divisions []*Division
+	for countryCode, divisionsByCode := range af.Divisions {
+		if division, exists := divisionsByCode[strings.ToUpper(region)]; exists {
+			divisions = append(divisions, division)
+		} else if code, exists := af.names[countryCode][strings.ToLower(region)]; exists {
+			divisions = append(divisions, divisionsByCode[code])
+		}
+	}
+	sort.Slice(divisions, func(i, j int) bool {
+		return divisions[i].CountryCode < divisions[j].CountryCode
+	})
+	return divisions
+}
+
+// Resolve turns a region given either as an admin code ("IL") or as a name ("Illinois") into its admin code
+func (af *Finder) Resolve(countryCode, region string) (string, bool) {
+	af.mutex.RLock()
+	defer af.mutex.RUnlock()
+
+	region = strings.TrimSpace(region)
+	if _, exists := af.Divisions[countryCode][strings.ToUpper(region)]; exists {
+		return strings.ToUpper(region), true
+	}
+	code, exists := af.names[countryCode][strings.ToLower(region)]
+	return code, exists
+}
@@ -0,0 +1,43 @@
+package admin
+
+import (
+	"testing"
+
+	"github.com/SamyRai/cityFinder/lib/dataLoader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFinder_Subdivisions(t *testing.T) {
+	finder := BuildIndex([]dataLoader.AdminCode{
+		{CountryCode: "US", Code: "IL", Name: "Illinois", ASCIIName: "Illinois", GeonameID: 4896861},
+		{CountryCode: "US", Code: "OH", Name: "Ohio", ASCIIName: "Ohio", GeonameID: 5165418},
+	}, []dataLoader.AdminCode{
+		{CountryCode: "US", Code: "IL.031", Name: "Cook County", ASCIIName: "Cook County", GeonameID: 4888671},
+		{CountryCode: "US", Code: "IL.043", Name: "DuPage County", ASCIIName: "DuPage County", GeonameID: 4890534},
+		{CountryCode: "US", Code: "OH.043", Name: "Erie County", ASCIIName: "Erie County", GeonameID: 5152833},
+		{CountryCode: "US", Code: "broken", Name: "No parent"},
+	})
+
+	subdivision := finder.Subdivision("US", "IL", "031")
+	require.NotNil(t, subdivision)
+	assert.Equal(t, "Cook County", subdivision.Name)
+	assert.Equal(t, "IL", subdivision.Admin1Code)
+	assert.Nil(t, finder.Subdivision("US", "OH", "031"))
+
+	admin1Code, admin2Code, ok := finder.ResolveSubregion("US", "", "cook county")
+	assert.True(t, ok)
+	assert.Equal(t, "IL", admin1Code)
+	assert.Equal(t, "031", admin2Code)
+
+	// The code 043 exists in two states and needs the state to be resolved
+	_, _, ok = finder.ResolveSubregion("US", "", "043")
+	assert.False(t, ok)
+	admin1Code, admin2Code, ok = finder.ResolveSubregion("US", "OH", "043")
+	assert.True(t, ok)
+	assert.Equal(t, "OH", admin1Code)
+	assert.Equal(t, "043", admin2Code)
+
+	_, _, ok = finder.ResolveSubregion("US", "OH", "Cook County")
+	assert.False(t, ok)
+}
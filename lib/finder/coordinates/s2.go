@@ -2,6 +2,7 @@ package coordinates
 
 import (
 	"encoding/gob"
+	"errors"
 	"fmt"
 	"os"
 
@@ -13,10 +14,18 @@ import (
 
 const earthRadiusKm = 6371.0
 
+// ErrNoPlace is returned when the index holds no place to search
+var ErrNoPlace = errors.New("no city found")
+
+// IndexVersion is the format version of the serialized S2 index. Bump it whenever the fields of the serialized
+// places change, so that index files written before are rebuilt instead of read with the new fields left empty.
+const IndexVersion = 1
+
 // S2Finder uses a ShapeIndex for efficient nearest neighbor searches.
 type S2Finder struct {
 	Index  *s2.ShapeIndex
 	Cities []city.City
+	ids    map[int]int // Map of geonameid to the position of the place in Cities
 }
 
 // SerializableS2Finder is a helper struct for gob encoding/decoding.
@@ -45,7 +54,26 @@ func BuildIndex(cities []city.SpatialCity, config *config.S2) (*S2Finder, error)
 	index := s2.NewShapeIndex()
 	index.Add(&points)
 
-	return &S2Finder{Index: index, Cities: cityData}, nil
+	return &S2Finder{Index: index, Cities: cityData, ids: geonameIDs(cityData)}, nil
+}
+
+// geonameIDs maps the geonameid of every place to its position
+func geonameIDs(cities []city.City) map[int]int {
+	ids := make(map[int]int, len(cities))
+	for i, c := range cities {
+		ids[c.GeonameID] = i
+	}
+	return ids
+}
+
+// PlaceByID returns the place with the given geonameid
+func (f *S2Finder) PlaceByID(geonameID int) (*city.City, bool) {
+	i, exists := f.ids[geonameID]
+	if !exists {
+		return nil, false
+	}
+	place := f.Cities[i]
+	return &place, true
 }
 
 // NearestPlace finds the nearest city to the given latitude and longitude.
@@ -59,7 +87,7 @@ func (f *S2Finder) NearestPlace(lat, lon float64) (*city.City, float64, error) {
 	results := query.FindEdges(target)
 
 	if len(results) == 0 {
-		return nil, 0, fmt.Errorf("no city found")
+		return nil, 0, ErrNoPlace
 	}
 
 	closest := results[0]
@@ -86,7 +114,10 @@ func (f *S2Finder) SerializeIndex(filepath string) error {
 	}
 
 	encoder := gob.NewEncoder(file)
-	encodeErr := encoder.Encode(serializable)
+	encodeErr := encoder.Encode(IndexVersion)
+	if encodeErr == nil {
+		encodeErr = encoder.Encode(serializable)
+	}
 	closeErr := file.Close()
 
 	if encodeErr != nil {
@@ -95,16 +126,22 @@ func (f *S2Finder) SerializeIndex(filepath string) error {
 	return closeErr
 }
 
-// DeserializeIndex loads the finder's data from a file.
+// DeserializeIndex loads the finder's data from a file. Files of another format version are rejected.
 func DeserializeIndex(filepath string) (*S2Finder, error) {
 	file, err := os.Open(filepath)
 	if err != nil {
 		return nil, fmt.Errorf("error opening file: %w", err)
 	}
 
+	var version int
 	var serializable SerializableS2Finder
 	decoder := gob.NewDecoder(file)
-	decodeErr := decoder.Decode(&serializable)
+	decodeErr := decoder.Decode(&version)
+	if decodeErr == nil && version != IndexVersion {
+		decodeErr = fmt.Errorf("index format version %d, expected %d", version, IndexVersion)
+	} else if decodeErr == nil {
+		decodeErr = decoder.Decode(&serializable)
+	}
 	closeErr := file.Close()
 
 	if decodeErr != nil {
@@ -125,5 +162,6 @@ func DeserializeIndex(filepath string) (*S2Finder, error) {
 	return &S2Finder{
 		Index:  index,
 		Cities: serializable.Cities,
+		ids:    geonameIDs(serializable.Cities),
 	}, nil
 }
\ No newline at end of file
@@ -1,6 +1,7 @@
 package coordinates
 
 import (
+	"encoding/gob"
 	"os"
 	"testing"
 
@@ -82,6 +83,26 @@ func TestSerialization(t *testing.T) {
 	assert.Equal(t, "San Francisco", nearest.Name)
 }
 
+func TestDeserializeIndex_OtherVersion(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "s2index_test.*.gob")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.Remove(tmpfile.Name())
+	}()
+
+	// Index files written before the format was versioned start with the places themselves
+	cities := make([]city.City, len(testCities))
+	for i, place := range testCities {
+		cities[i] = place.City
+	}
+	err = gob.NewEncoder(tmpfile).Encode(SerializableS2Finder{Cities: cities})
+	assert.NoError(t, err)
+	assert.NoError(t, tmpfile.Close())
+
+	_, err = DeserializeIndex(tmpfile.Name())
+	assert.Error(t, err)
+}
+
 func TestEmptyCities(t *testing.T) {
 	cfg := &config.S2{}
 	finder, err := BuildIndex([]city.SpatialCity{}, cfg)
@@ -89,7 +110,7 @@ func TestEmptyCities(t *testing.T) {
 	assert.NotNil(t, finder)
 
 	_, _, err = finder.NearestPlace(0, 0)
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrNoPlace)
 	assert.Equal(t, "no city found", err.Error())
 }
 
@@ -15,11 +15,25 @@ var ErrNoResults = errors.New("no results found")
 
 // SerializableSpatialCity is a custom type for serializing city.SpatialCity
 type SerializableSpatialCity struct {
-	Latitude  float64
-	Longitude float64
-	Name      string
-	Country   string
-	Rect      *city.Rect
+	GeonameID        int
+	Latitude         float64
+	Longitude        float64
+	Name             string
+	ASCIIName        string
+	Country          string
+	CC2              []string
+	FeatureClass     string
+	FeatureCode      string
+	Admin1Code       string
+	Admin2Code       string
+	Admin3Code       string
+	Admin4Code       string
+	Population       int64
+	Elevation        int
+	DEM              int
+	Timezone         string
+	ModificationDate string
+	Rect             *city.Rect
 }
 
 // CityReader provides random access to a gob-encoded file of cities.
@@ -73,11 +87,25 @@ func (r *CityReader) Close() error {
 // FromSpatialCity converts city.SpatialCity to SerializableSpatialCity
 func FromSpatialCity(sc city.SpatialCity) SerializableSpatialCity {
 	return SerializableSpatialCity{
-		Latitude:  sc.Latitude,
-		Longitude: sc.Longitude,
-		Name:      sc.Name,
-		Country:   sc.Country,
-		Rect:      sc.Rect,
+		GeonameID:        sc.GeonameID,
+		Latitude:         sc.Latitude,
+		Longitude:        sc.Longitude,
+		Name:             sc.Name,
+		ASCIIName:        sc.ASCIIName,
+		Country:          sc.Country,
+		CC2:              sc.CC2,
+		FeatureClass:     sc.FeatureClass,
+		FeatureCode:      sc.FeatureCode,
+		Admin1Code:       sc.Admin1Code,
+		Admin2Code:       sc.Admin2Code,
+		Admin3Code:       sc.Admin3Code,
+		Admin4Code:       sc.Admin4Code,
+		Population:       sc.Population,
+		Elevation:        sc.Elevation,
+		DEM:              sc.DEM,
+		Timezone:         sc.Timezone,
+		ModificationDate: sc.ModificationDate,
+		Rect:             sc.Rect,
 	}
 }
 
@@ -85,10 +113,24 @@ func FromSpatialCity(sc city.SpatialCity) SerializableSpatialCity {
 func ToSpatialCity(ssc SerializableSpatialCity) (city.SpatialCity, error) {
 	return city.SpatialCity{
 		City: city.City{
-			Latitude:  ssc.Latitude,
-			Longitude: ssc.Longitude,
-			Name:      ssc.Name,
-			Country:   ssc.Country,
+			GeonameID:        ssc.GeonameID,
+			Latitude:         ssc.Latitude,
+			Longitude:        ssc.Longitude,
+			Name:             ssc.Name,
+			ASCIIName:        ssc.ASCIIName,
+			Country:          ssc.Country,
+			CC2:              ssc.CC2,
+			FeatureClass:     ssc.FeatureClass,
+			FeatureCode:      ssc.FeatureCode,
+			Admin1Code:       ssc.Admin1Code,
+			Admin2Code:       ssc.Admin2Code,
+			Admin3Code:       ssc.Admin3Code,
+			Admin4Code:       ssc.Admin4Code,
+			Population:       ssc.Population,
+			Elevation:        ssc.Elevation,
+			DEM:              ssc.DEM,
+			Timezone:         ssc.Timezone,
+			ModificationDate: ssc.ModificationDate,
 		},
 		Rect: ssc.Rect,
 	}, nil
@@ -0,0 +1,116 @@
+package country
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/SamyRai/cityFinder/lib/dataLoader"
+)
+
+// Country is the metadata of a country from the GeoNames country table
+type Country struct {
+	ISO              string // ISO 3166-1 alpha-2 code, used as the country code throughout
+	ISO3             string
+	ISONumeric       string
+	FIPS             string
+	Name             string
+	Capital          string
+	AreaKm2          float64
+	Population       int64
+	Continent        string
+	TLD              string
+	CurrencyCode     string
+	CurrencyName     string
+	Phone            string
+	PostalCodeFormat string
+	PostalCodeRegex  string
+	Languages        []string
+	GeonameID        int
+	Neighbours       []string
+}
+
+// Finder looks up countries by their ISO codes
+type Finder struct {
+	Countries map[string]*Country // Map of ISO alpha-2 code to country
+	aliases   map[string]string   // Map of ISO alpha-3 and numeric code to alpha-2 code
+	mutex     sync.RWMutex        // Mutex for thread-safe operations
+}
+
+// NewCountryFinder creates a new Finder instance
+func NewCountryFinder() *Finder {
+	return &Finder{
+		Countries: make(map[string]*Country),
+		aliases:   make(map[string]string),
+	}
+}
+
+// BuildIndex creates a country index from the GeoNames country table
+func BuildIndex(countries []dataLoader.CountryInfo) *Finder {
+	finder := NewCountryFinder()
+	for _, info := range countries {
+		finder.AddCountry(Country(info))
+	}
+	return finder
+}
+
+// AddCountry adds a country to the Finder
+func (cf *Finder) AddCountry(country Country) {
+	cf.mutex.Lock()
+	defer cf.mutex.Unlock()
+
+	cf.Countries[country.ISO] = &country
+	if country.ISO3 != "" {
+		cf.aliases[country.ISO3] = country.ISO
+	}
+	if country.ISONumeric != "" {
+		cf.aliases[country.ISONumeric] = country.ISO
+	}
+}
+
+// Country returns the country with an ISO alpha-2, alpha-3 or numeric code, regardless of case
+func (cf *Finder) Country(code string) (*Country, bool) {
+	cf.mutex.RLock()
+	defer cf.mutex.RUnlock()
+
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if iso, exists := cf.aliases[code]; exists {
+		code = iso
+	}
+	country, exists := cf.Countries[code]
+	return country, exists
+}
+
+// Exists reports whether an ISO alpha-2 code is a known country
+func (cf *Finder) Exists(code string) bool {
+	cf.mutex.RLock()
+	defer cf.mutex.RUnlock()
+
+	_, exists := cf.Countries[code]
+	return exists
+}
+
+// List returns all countries ordered by their ISO code, optionally only those of a continent
+func (cf *Finder) List(continent string) []*Country {
+	cf.mutex.RLock()
+	defer cf.mutex.RUnlock()
+
+	countries := make([]*Country, 0, len(cf.Countries))
+	for _, country := range cf.Countries {
+		if continent == "" || strings.EqualFold(country.Continent, continent) {
+			countries = append(countries, country)
+		}
+	}
+	sort.Slice(countries, func(i, j int) bool {
+		return countries[i].ISO < countries[j].ISO
+	})
+	return countries
+}
+
+// Len returns the number of known countries
+func (cf *Finder) Len() int {
+	cf.mutex.RLock()
+	defer cf.mutex.RUnlock()
+
+	return len(cf.Countries)
+}
@@ -1,23 +1,48 @@
 package finder
 
 import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
 	"github.com/SamyRai/cityFinder/lib/city"
 	"github.com/SamyRai/cityFinder/lib/config"
 	"github.com/SamyRai/cityFinder/lib/dataLoader"
+	"github.com/SamyRai/cityFinder/lib/finder/admin"
 	"github.com/SamyRai/cityFinder/lib/finder/coordinates"
+	"github.com/SamyRai/cityFinder/lib/finder/country"
+	"github.com/SamyRai/cityFinder/lib/finder/hierarchy"
 	"github.com/SamyRai/cityFinder/lib/finder/name"
 	"github.com/SamyRai/cityFinder/lib/finder/postalCode"
+	"github.com/SamyRai/cityFinder/lib/finder/timezone"
 )
 
+// ErrUnknownRegion is returned when a region matches neither an admin code nor a division name
+var ErrUnknownRegion = errors.New("unknown region")
+
+// ErrUnknownPlace is returned when no place has the requested geonameid
+var ErrUnknownPlace = errors.New("unknown place")
+
+// ErrUnknownPostalCode is returned when a postal code is not in the index of its country
+var ErrUnknownPostalCode = errors.New("unknown postal code")
+
 // Finder struct embeds all individual finders
 type Finder struct {
-	S2Finder         *coordinates.S2Finder
-	NameFinder       *name.Finder
-	PostalCodeFinder *postalCode.Finder
+	S2Finder               *coordinates.S2Finder
+	NameFinder             *name.Finder
+	PostalCodeFinder       *postalCode.Finder
+	PostalCodeSpatialIndex *postalCode.SpatialIndex // Optional S2 index over the postal code centroids
+	AdminFinder            *admin.Finder
+	CountryFinder          *country.Finder
+	HierarchyFinder        *hierarchy.Finder
+	TimezoneFinder         *timezone.Finder // Optional time zone boundary polygons
+	adminChildren          map[int][]int    // Map of division geonameid to the places below it through their admin codes, built on first use
+	adminChildrenOnce      sync.Once        // Guards the building of adminChildren
 }
 
 // NewFinder creates a new Finder instance
-func NewFinder(cities []city.SpatialCity, s2Config *config.S2, postalCodes map[string]map[string]dataLoader.PostalCodeEntry) (*Finder, error) {
+func NewFinder(cities []city.SpatialCity, s2Config *config.S2, postalCodes map[string]map[string][]dataLoader.PostalCodeEntry, admin1Codes, admin2Codes []dataLoader.AdminCode) (*Finder, error) {
 
 	s2Finder, err := coordinates.NewS2Finder(s2Config)
 	if err != nil {
@@ -31,22 +56,145 @@ func NewFinder(cities []city.SpatialCity, s2Config *config.S2, postalCodes map[s
 		nameFinder.AddCity(spatialCity)
 	}
 
+	postalCode.LinkPlaces(postalCodes, cities)
 	for _, postalCodeEntries := range postalCodes {
-		for _, entry := range postalCodeEntries {
-			postalCodeFinder.AddPostalCode(entry)
+		for _, entries := range postalCodeEntries {
+			for _, entry := range entries {
+				postalCodeFinder.AddPostalCode(entry)
+			}
 		}
 	}
 
 	return &Finder{
-		S2Finder:         s2Finder,
-		NameFinder:       nameFinder,
-		PostalCodeFinder: postalCodeFinder,
+		S2Finder:               s2Finder,
+		NameFinder:             nameFinder,
+		PostalCodeFinder:       postalCodeFinder,
+		PostalCodeSpatialIndex: postalCode.BuildSpatialIndex(postalCodeFinder),
+		AdminFinder:            admin.BuildIndex(admin1Codes, admin2Codes),
 	}, nil
 }
 
-// FindCityByPostalCode wraps the PostalCodeFinder method
-func (f *Finder) FindCityByPostalCode(postalCode, countryCode string) *city.City {
-	return f.PostalCodeFinder.CityByPostalCode(postalCode, countryCode)
+// KnownCountry reports whether a country code is in the country table. Every code is accepted when no table is loaded.
+func (f *Finder) KnownCountry(countryCode string) bool {
+	if f.CountryFinder == nil || f.CountryFinder.Len() == 0 {
+		return true
+	}
+	return f.CountryFinder.Exists(countryCode)
+}
+
+// Place is a GeoNames place together with its administrative divisions and the postal codes linked to it
+type Place struct {
+	city.City
+	Region      *admin.Division // First-order division, nil if unknown
+	Subregion   *admin.Division // Second-order division, nil if unknown
+	PostalCodes []string
+}
+
+// Describe returns a place with its divisions and postal codes
+func (f *Finder) Describe(c *city.City) Place {
+	place := Place{City: *c}
+	place.Region, place.Subregion = f.divisions(c)
+	if f.PostalCodeFinder != nil {
+		place.PostalCodes = f.PostalCodeFinder.PostalCodes(c.GeonameID)
+	}
+	return place
+}
+
+// divisions resolves the admin codes of a place to its first and second-order divisions
+func (f *Finder) divisions(c *city.City) (*admin.Division, *admin.Division) {
+	if f.AdminFinder == nil {
+		return nil, nil
+	}
+	return f.AdminFinder.Division(c.Country, c.Admin1Code), f.AdminFinder.Subdivision(c.Country, c.Admin1Code, c.Admin2Code)
+}
+
+// postalDivisions resolves the admin codes of a postal code place to its divisions. The postal code tables
+// of some countries use admin codes of their own, so when those do not resolve, the divisions of the GeoNames place
+// the postal code is linked to are taken instead.
+func (f *Finder) postalDivisions(c *city.City) (*admin.Division, *admin.Division) {
+	region, subregion := f.divisions(c)
+	if region != nil || f.S2Finder == nil || c.GeonameID == 0 {
+		return region, subregion
+	}
+	if linked, ok := f.S2Finder.PlaceByID(c.GeonameID); ok {
+		return f.divisions(linked)
+	}
+	return region, subregion
+}
+
+// PostalCodePlace is a postal code place together with its administrative divisions
+type PostalCodePlace struct {
+	postalCode.Place
+	Region    *admin.Division // First-order division, nil if unknown
+	Subregion *admin.Division // Second-order division, nil if unknown
+}
+
+// NearbyPostalCode is a postal code place together with its divisions and its distance from the searched point
+type NearbyPostalCode struct {
+	PostalCodePlace
+	DistanceKm float64
+}
+
+// describePostalCodes returns the postal code places with their divisions
+func (f *Finder) describePostalCodes(places []postalCode.Place) []PostalCodePlace {
+	described := make([]PostalCodePlace, len(places))
+	for i, place := range places {
+		described[i] = PostalCodePlace{Place: place}
+		described[i].Region, described[i].Subregion = f.postalDivisions(&place.City)
+	}
+	return described
+}
+
+// describeNearbyPostalCodes returns the nearby postal code places with their divisions
+func (f *Finder) describeNearbyPostalCodes(places []postalCode.NearbyPlace, err error) ([]NearbyPostalCode, error) {
+	if err != nil {
+		return nil, err
+	}
+	described := make([]NearbyPostalCode, len(places))
+	for i, place := range places {
+		described[i] = NearbyPostalCode{PostalCodePlace: PostalCodePlace{Place: place.Place}, DistanceKm: place.DistanceKm}
+		described[i].Region, described[i].Subregion = f.postalDivisions(&place.City)
+	}
+	return described, nil
+}
+
+// FindCityByPostalCode wraps the PostalCodeFinder method and drops the places less accurate than minAccuracy
+func (f *Finder) FindCityByPostalCode(code, countryCode string, minAccuracy int) []PostalCodePlace {
+	return f.describePostalCodes(postalCode.FilterByAccuracy(f.PostalCodeFinder.CityByPostalCode(code, countryCode), minAccuracy))
+}
+
+// FindPostalCodesByName wraps the PostalCodeFinder place name lookup and drops the places less accurate than minAccuracy
+func (f *Finder) FindPostalCodesByName(placeName, countryCode, region string, minAccuracy int) []PostalCodePlace {
+	return f.describePostalCodes(postalCode.FilterByAccuracy(f.PostalCodeFinder.ByPlaceName(placeName, countryCode, region), minAccuracy))
+}
+
+// ValidatePostalCode wraps the PostalCodeFinder validation
+func (f *Finder) ValidatePostalCode(code, countryCode string) postalCode.Validation {
+	return f.PostalCodeFinder.Validate(code, countryCode)
+}
+
+// SearchPostalCodePrefix wraps the PostalCodeFinder prefix search
+func (f *Finder) SearchPostalCodePrefix(prefix, countryCode string, limit int) postalCode.SearchResult {
+	return f.PostalCodeFinder.SearchPrefix(prefix, countryCode, limit)
+}
+
+// SearchPostalCodeRange wraps the PostalCodeFinder range search
+func (f *Finder) SearchPostalCodeRange(from, to, countryCode string, limit int) (postalCode.SearchResult, error) {
+	return f.PostalCodeFinder.SearchRange(from, to, countryCode, limit)
+}
+
+// FindNearestPostalCodes wraps the postal code S2 index and returns the postal code places closest to a point
+func (f *Finder) FindNearestPostalCodes(lat, lon float64, limit, minAccuracy int) ([]NearbyPostalCode, error) {
+	return f.describeNearbyPostalCodes(f.PostalCodeSpatialIndex.Nearest(lat, lon, limit, 0, minAccuracy))
+}
+
+// FindPostalCodesAround returns the postal codes within a radius of the centroid of another postal code, nearest first
+func (f *Finder) FindPostalCodesAround(code, countryCode string, radiusKm float64, minAccuracy int) ([]NearbyPostalCode, error) {
+	lat, lon, ok := f.PostalCodeFinder.Centroid(code, countryCode)
+	if !ok {
+		return nil, ErrUnknownPostalCode
+	}
+	return f.describeNearbyPostalCodes(f.PostalCodeSpatialIndex.Around(lat, lon, radiusKm, minAccuracy))
 }
 
 // FindCityByName wraps the NameFinder method
@@ -54,6 +202,125 @@ func (f *Finder) FindCityByName(name, countryCode string) *city.City {
 	return f.NameFinder.CityByName(name, countryCode)
 }
 
+// NameQuery builds a name lookup from free text such as "Springfield, Illinois, US".
+// Trailing comma-separated parts are read as region and country code, and the region,
+// given either here or in the text, is resolved to its admin1 code through the admin code tables.
+// A subregion, such as a county, is resolved to its admin2 code within the region if one is given.
+// When a region read from the text does not resolve, the comma is taken to be part of the name,
+// as in "Main, Frankfurt am", and the whole text before the country code is looked up instead.
+func (f *Finder) NameQuery(text, countryCode, region, subregion string) (name.Query, error) {
+	parts := strings.Split(text, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+
+	query := name.Query{Name: parts[0], CountryCode: countryCode}
+	rest := parts[1:]
+	if len(rest) > 0 {
+		last := rest[len(rest)-1]
+		// A lone trailing code is a region when the country is already known, e.g. "Springfield, IL" in the US
+		if isCountryCode(last) && (countryCode == "" || len(rest) > 1 || strings.EqualFold(last, countryCode)) {
+			query.CountryCode = strings.ToUpper(last)
+			rest = rest[:len(rest)-1]
+		}
+	}
+	regionInText := region == "" && len(rest) > 0
+	if regionInText {
+		region = rest[len(rest)-1]
+	}
+
+	if region != "" && query.CountryCode != "" {
+		code, ok := "", false
+		if f.AdminFinder != nil {
+			code, ok = f.AdminFinder.Resolve(query.CountryCode, region)
+		}
+		switch {
+		case ok:
+			query.Admin1Code = code
+		case regionInText:
+			query.Name = strings.Join(append(parts[:1:1], rest...), ", ")
+		default:
+			return query, ErrUnknownRegion
+		}
+	}
+
+	if subregion != "" && query.CountryCode != "" {
+		if f.AdminFinder == nil {
+			return query, ErrUnknownRegion
+		}
+		admin1Code, admin2Code, ok := f.AdminFinder.ResolveSubregion(query.CountryCode, query.Admin1Code, subregion)
+		if !ok {
+			return query, ErrUnknownRegion
+		}
+		query.Admin1Code, query.Admin2Code = admin1Code, admin2Code
+	}
+	return query, nil
+}
+
+func isCountryCode(value string) bool {
+	if len(value) != 2 {
+		return false
+	}
+	for _, r := range value {
+		if (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') {
+			return false
+		}
+	}
+	return true
+}
+
+// SearchCitiesByName wraps the NameFinder search and returns all candidates, best first
+func (f *Finder) SearchCitiesByName(q name.Query) []*city.City {
+	return f.NameFinder.Search(q)
+}
+
+// SuggestCityNames wraps the NameFinder suggestions for names that were not found
+func (f *Finder) SuggestCityNames(cityName, countryCode string, limit int) []name.Suggestion {
+	return f.NameFinder.Suggest(cityName, countryCode, limit)
+}
+
+// PatternMatch is a place matching a name pattern together with its administrative divisions
+type PatternMatch struct {
+	name.PatternMatch
+	Region    *admin.Division // First-order division, nil if unknown
+	Subregion *admin.Division // Second-order division, nil if unknown
+}
+
+// PatternResult holds the matches of a pattern search in the order of name.PatternResult
+type PatternResult struct {
+	Matches   []PatternMatch
+	Truncated bool // The limit was reached before all names were scanned
+}
+
+// SearchNamePatterns wraps the NameFinder glob and regular expression search and resolves the divisions of the matches
+func (f *Finder) SearchNamePatterns(ctx context.Context, q name.PatternQuery) (PatternResult, error) {
+	found, err := f.NameFinder.SearchPattern(ctx, q)
+	if err != nil {
+		return PatternResult{}, err
+	}
+	result := PatternResult{Matches: make([]PatternMatch, len(found.Matches)), Truncated: found.Truncated}
+	for i, match := range found.Matches {
+		result.Matches[i] = PatternMatch{PatternMatch: match}
+		result.Matches[i].Region, result.Matches[i].Subregion = f.divisions(match.City)
+	}
+	return result, nil
+}
+
+// Localize returns a copy of the city named in the first of the given languages it is known in,
+// or the city itself when there is no such name
+func (f *Finder) Localize(c *city.City, languages []string) *city.City {
+	if c == nil || len(languages) == 0 {
+		return c
+	}
+	localizedName, ok := f.NameFinder.LocalizedName(c.GeonameID, languages...)
+	if !ok {
+		return c
+	}
+	localized := *c
+	localized.Name = localizedName
+	return &localized
+}
+
 // FindNearestCity wraps the S2Finder method
 func (f *Finder) FindNearestCity(lat, lon float64) (*city.City, float64, error) {
 	c, dist, err := f.S2Finder.NearestPlace(lat, lon)
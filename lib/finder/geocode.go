@@ -0,0 +1,240 @@
+package finder
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/SamyRai/cityFinder/lib/city"
+	"github.com/SamyRai/cityFinder/lib/finder/admin"
+	"github.com/SamyRai/cityFinder/lib/finder/name"
+	"github.com/SamyRai/cityFinder/lib/finder/postalCode"
+)
+
+// Sources of geocoding matches
+const (
+	SourceName       = "name"
+	SourcePostalCode = "postalCode"
+)
+
+// ParsedQuery is one reading of a free-text location
+type ParsedQuery struct {
+	Name        string
+	Region      string
+	Admin1Code  string
+	CountryCode string
+	PostalCode  string
+}
+
+// GeocodeMatch is a place found for a free-text location, together with the reading it was found with
+type GeocodeMatch struct {
+	Place     *city.City
+	Region    *admin.Division // First-order division of the place, nil if unknown
+	Subregion *admin.Division // Second-order division of the place, nil if unknown
+	Query     ParsedQuery
+	Source    string // SourceName or SourcePostalCode
+}
+
+// ParseQuery splits a free-text location such as "Paris, TX", "Berlin Germany", "10115 Berlin" or
+// "Kazan, Tatarstan, RU" into name, region, country and postal code. Text that can be read in several ways
+// yields several readings, most specific first: "Paris, IL" is both Paris in Israel and Paris in Illinois.
+func (f *Finder) ParseQuery(text string) []ParsedQuery {
+	var parts []string
+	for _, part := range strings.Split(text, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	postalCode, parts := extractPostalCode(parts)
+	if len(parts) == 0 {
+		if postalCode == "" {
+			return nil
+		}
+		return []ParsedQuery{{PostalCode: postalCode}}
+	}
+
+	var readings []ParsedQuery
+	if len(parts) > 1 {
+		last := parts[len(parts)-1]
+		countryCode, isCountry := f.countryOf(last)
+		if isCountry {
+			reading := ParsedQuery{Name: parts[0], CountryCode: countryCode, PostalCode: postalCode}
+			if len(parts) > 2 {
+				reading.Region = parts[len(parts)-2]
+			}
+			readings = append(readings, reading)
+		}
+		// The last part may as well be a region of a country left unnamed
+		if !isCountry || len(parts) == 2 {
+			readings = append(readings, ParsedQuery{Name: parts[0], Region: last, PostalCode: postalCode})
+		}
+		return readings
+	}
+
+	// Without commas only a trailing country name or upper-case country code can be told apart from the name
+	words := strings.Fields(parts[0])
+	for n := min(3, len(words)-1); n >= 1; n-- {
+		trailing := strings.Join(words[len(words)-n:], " ")
+		if isCountryCode(trailing) && trailing != strings.ToUpper(trailing) {
+			continue
+		}
+		if countryCode, ok := f.countryOf(trailing); ok {
+			readings = append(readings, ParsedQuery{Name: strings.Join(words[:len(words)-n], " "), CountryCode: countryCode, PostalCode: postalCode})
+			break
+		}
+	}
+	return append(readings, ParsedQuery{Name: parts[0], PostalCode: postalCode})
+}
+
+// Geocode finds the places matching a free-text location. The readings of the text are looked up in turn
+// and their results merged, best first. A postal code narrows the search to the countries it exists in and
+// ranks same-named places by their distance to it; a postal code alone resolves to its own place.
+func (f *Finder) Geocode(text string) []GeocodeMatch {
+	type placeKey struct {
+		name     string
+		lat, lon float64
+	}
+	seen := make(map[placeKey]bool)
+	matches := make([]GeocodeMatch, 0)
+	for _, reading := range f.ParseQuery(text) {
+		for _, match := range f.geocode(reading) {
+			key := placeKey{match.Place.Name, match.Place.Latitude, match.Place.Longitude}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if match.Source == SourcePostalCode {
+				match.Region, match.Subregion = f.postalDivisions(match.Place)
+			} else {
+				match.Region, match.Subregion = f.divisions(match.Place)
+			}
+			matches = append(matches, match)
+		}
+	}
+	return matches
+}
+
+// geocode looks up a single reading in every country and division it may refer to
+func (f *Finder) geocode(reading ParsedQuery) []GeocodeMatch {
+	type scope struct{ countryCode, admin1Code string }
+	var scopes []scope
+	switch {
+	case reading.CountryCode != "":
+		s := scope{countryCode: reading.CountryCode}
+		if reading.Region != "" {
+			if f.AdminFinder == nil {
+				return nil
+			}
+			code, ok := f.AdminFinder.Resolve(reading.CountryCode, reading.Region)
+			if !ok {
+				return nil
+			}
+			s.admin1Code = code
+		}
+		scopes = append(scopes, s)
+	case reading.Region != "":
+		if f.AdminFinder == nil {
+			return nil
+		}
+		for _, division := range f.AdminFinder.Lookup(reading.Region) {
+			scopes = append(scopes, scope{division.CountryCode, division.Code})
+		}
+	case reading.PostalCode != "":
+		for _, countryCode := range f.PostalCodeFinder.Countries(reading.PostalCode) {
+			scopes = append(scopes, scope{countryCode: countryCode})
+		}
+	default:
+		// Nothing narrows the search down, so only exact matches across all countries are worth returning.
+		// They are looked up in one pass rather than by a search per country.
+		var matches []GeocodeMatch
+		for _, place := range f.NameFinder.SearchAllCountries(reading.Name) {
+			resolved := reading
+			resolved.CountryCode = place.Country
+			matches = append(matches, GeocodeMatch{Place: place, Query: resolved, Source: SourceName})
+		}
+		return matches
+	}
+
+	var matches []GeocodeMatch
+	for _, s := range scopes {
+		resolved := reading
+		resolved.CountryCode, resolved.Admin1Code = s.countryCode, s.admin1Code
+
+		var postalPlaces []postalCode.Place
+		query := name.Query{Name: reading.Name, CountryCode: s.countryCode, Admin1Code: s.admin1Code}
+		if reading.PostalCode != "" {
+			if postalPlaces = f.PostalCodeFinder.CityByPostalCode(reading.PostalCode, s.countryCode); len(postalPlaces) > 0 {
+				query.Near = &name.Location{Latitude: postalPlaces[0].Latitude, Longitude: postalPlaces[0].Longitude}
+			}
+		}
+
+		var places []*city.City
+		if reading.Name != "" {
+			places = f.NameFinder.Search(query)
+		}
+		for _, place := range places {
+			matches = append(matches, GeocodeMatch{Place: place, Query: resolved, Source: SourceName})
+		}
+		if len(places) == 0 {
+			for i := range postalPlaces {
+				matches = append(matches, GeocodeMatch{Place: &postalPlaces[i].City, Query: resolved, Source: SourcePostalCode})
+			}
+		}
+	}
+	return matches
+}
+
+// countryOf resolves a country given by its code or by its name
+func (f *Finder) countryOf(value string) (string, bool) {
+	if isCountryCode(value) {
+		if code := strings.ToUpper(value); f.NameFinder.HasCountry(code) {
+			return code, true
+		}
+		return "", false
+	}
+	return f.NameFinder.CountryByName(value)
+}
+
+// extractPostalCode takes a postal code off the start of the first part or the end of the last part,
+// e.g. "10115 Berlin" or "Berlin, 10115". Postal codes are the words that contain a digit, such as "AD500" or "K1A 0B1".
+func extractPostalCode(parts []string) (string, []string) {
+	if len(parts) == 0 {
+		return "", parts
+	}
+
+	words := strings.Fields(parts[0])
+	n := 0
+	for n < len(words) && hasDigit(words[n]) {
+		n++
+	}
+	if n > 0 {
+		postalCode := strings.Join(words[:n], " ")
+		return postalCode, replaceFirst(parts, strings.Join(words[n:], " "))
+	}
+
+	words = strings.Fields(parts[len(parts)-1])
+	n = len(words)
+	for n > 0 && hasDigit(words[n-1]) {
+		n--
+	}
+	if n < len(words) {
+		postalCode := strings.Join(words[n:], " ")
+		rest := append([]string{}, parts[:len(parts)-1]...)
+		if n > 0 {
+			rest = append(rest, strings.Join(words[:n], " "))
+		}
+		return postalCode, rest
+	}
+	return "", parts
+}
+
+// replaceFirst returns the parts with the first one replaced, or dropped if the replacement is empty
+func replaceFirst(parts []string, first string) []string {
+	if first == "" {
+		return parts[1:]
+	}
+	return append([]string{first}, parts[1:]...)
+}
+
+func hasDigit(word string) bool {
+	return strings.IndexFunc(word, unicode.IsDigit) >= 0
+}
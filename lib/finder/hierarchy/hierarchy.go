@@ -0,0 +1,95 @@
+package hierarchy
+
+import (
+	"sync"
+
+	"github.com/SamyRai/cityFinder/lib/dataLoader"
+)
+
+// AdministrativeType is the link type of the administrative hierarchy, preferred when walking up the tree
+const AdministrativeType = "ADM"
+
+// Link is an edge of the hierarchy from a place to its parent or child
+type Link struct {
+	GeonameID int
+	Type      string
+}
+
+// Finder walks the GeoNames place hierarchy
+type Finder struct {
+	Parents  map[int][]Link // Map of geonameid to its parents
+	Children map[int][]Link // Map of geonameid to its children, in file order
+	mutex    sync.RWMutex   // Mutex for thread-safe operations
+}
+
+// NewHierarchyFinder creates a new Finder instance
+func NewHierarchyFinder() *Finder {
+	return &Finder{
+		Parents:  make(map[int][]Link),
+		Children: make(map[int][]Link),
+	}
+}
+
+// BuildIndex creates a hierarchy index from the GeoNames hierarchy table
+func BuildIndex(links []dataLoader.HierarchyLink) *Finder {
+	finder := NewHierarchyFinder()
+	for _, link := range links {
+		finder.AddLink(link)
+	}
+	return finder
+}
+
+// AddLink adds a parent-child edge to the Finder
+func (hf *Finder) AddLink(link dataLoader.HierarchyLink) {
+	hf.mutex.Lock()
+	defer hf.mutex.Unlock()
+
+	hf.Parents[link.ChildID] = append(hf.Parents[link.ChildID], Link{GeonameID: link.ParentID, Type: link.Type})
+	hf.Children[link.ParentID] = append(hf.Children[link.ParentID], Link{GeonameID: link.ChildID, Type: link.Type})
+}
+
+// Ancestors returns the geonameids above a place, nearest first, e.g. county, state, country and continent.
+// Where a place has several parents the administrative one is followed.
+func (hf *Finder) Ancestors(geonameID int) []int {
+	hf.mutex.RLock()
+	defer hf.mutex.RUnlock()
+
+	var ancestors []int
+	seen := map[int]bool{geonameID: true}
+	for {
+		parent, ok := hf.parent(geonameID)
+		// The table is user-editable, so a cycle must not loop forever
+		if !ok || seen[parent] {
+			return ancestors
+		}
+		seen[parent] = true
+		ancestors = append(ancestors, parent)
+		geonameID = parent
+	}
+}
+
+// parent picks the administrative parent of a place, or its first parent if it has none
+func (hf *Finder) parent(geonameID int) (int, bool) {
+	parents := hf.Parents[geonameID]
+	if len(parents) == 0 {
+		return 0, false
+	}
+	for _, link := range parents {
+		if link.Type == AdministrativeType {
+			return link.GeonameID, true
+		}
+	}
+	return parents[0].GeonameID, true
+}
+
+// ChildIDs returns the geonameids directly below a place, in file order
+func (hf *Finder) ChildIDs(geonameID int) []int {
+	hf.mutex.RLock()
+	defer hf.mutex.RUnlock()
+
+	children := make([]int, 0, len(hf.Children[geonameID]))
+	for _, link := range hf.Children[geonameID] {
+		children = append(children, link.GeonameID)
+	}
+	return children
+}
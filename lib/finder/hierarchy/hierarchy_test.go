@@ -0,0 +1,27 @@
+package hierarchy
+
+import (
+	"testing"
+
+	"github.com/SamyRai/cityFinder/lib/dataLoader"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFinder_Ancestors(t *testing.T) {
+	finder := BuildIndex([]dataLoader.HierarchyLink{
+		{ParentID: 6295630, ChildID: 6255148},
+		{ParentID: 6255148, ChildID: 6252001},
+		{ParentID: 6252001, ChildID: 4896861, Type: "ADM"},
+		{ParentID: 4896861, ChildID: 4888671, Type: "ADM"},
+		{ParentID: 9999999, ChildID: 4888671, Type: "X"},
+		{ParentID: 4888671, ChildID: 4887398, Type: "ADM"},
+		{ParentID: 1, ChildID: 2},
+		{ParentID: 2, ChildID: 1},
+	})
+
+	assert.Equal(t, []int{4888671, 4896861, 6252001, 6255148, 6295630}, finder.Ancestors(4887398))
+	assert.Equal(t, []int{2}, finder.Ancestors(1))
+	assert.Empty(t, finder.Ancestors(6295630))
+	assert.Equal(t, []int{4896861}, finder.ChildIDs(6252001))
+	assert.Empty(t, finder.ChildIDs(4887398))
+}
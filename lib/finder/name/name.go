@@ -1,41 +1,179 @@
 package name
 
 import (
+	"cmp"
 	"encoding/gob"
 	"fmt"
 	"github.com/SamyRai/cityFinder/lib/city"
+	"github.com/SamyRai/cityFinder/lib/dataLoader"
 	"github.com/SamyRai/cityFinder/util"
 	"github.com/cheggaaa/pb/v3"
 	"os"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 )
 
+// IndexVersion is the format version of the serialized name index. Bump it whenever the layout of the index
+// or the way names are keyed changes, so that index files written before are rebuilt instead of misread.
+const IndexVersion = 5
+
+// IndexSettings are the configuration options a name index is built with. They are stored in the header of the
+// index file, and files built with other settings are rejected, so that changing them rebuilds the index.
+type IndexSettings struct {
+	FuzzyEngine        string   // Fuzzy matching backend, either FuzzyBKTree or FuzzySymSpell, empty selects FuzzyBKTree
+	AlternateNamesFile string   // Alternate names table the localized names were imported from, empty if none
+	Languages          []string // Languages the localized names were limited to, empty for all of them
+}
+
+// matches reports whether an index built with these settings is the one the other settings would build
+func (s IndexSettings) matches(other IndexSettings) bool {
+	return cmp.Or(s.FuzzyEngine, FuzzyBKTree) == cmp.Or(other.FuzzyEngine, FuzzyBKTree) &&
+		s.AlternateNamesFile == other.AlternateNamesFile &&
+		slices.Equal(languageSet(s.Languages), languageSet(other.Languages))
+}
+
+// languageSet returns the distinct languages in lower case and alphabetical order
+func languageSet(languages []string) []string {
+	set := make([]string, len(languages))
+	for i, language := range languages {
+		set[i] = strings.ToLower(language)
+	}
+	slices.Sort(set)
+	return slices.Compact(set)
+}
+
 // Finder is a struct that contains the data for city name lookups
 type Finder struct {
 	InvertedIndex map[string]map[string][]*city.City // Inverted index for city name lookups by country
-	BKTree        *util.BKTree                       // BK-tree for fuzzy city name matching
+	KeyIndex      map[string]map[string][]*city.City // Inverted index of canonical name keys, and of their sorted tokens, by country
+	PhoneticIndex map[string]map[string][]*city.City // Inverted index of phonetic codes of the name keys by country
+	SkeletonIndex map[string]map[string][]*city.City // Inverted index of consonant skeletons of the keys of names not in Arabic script by country
+	AbjadIndex    map[string]map[string][]*city.City // Inverted index of consonant skeletons of the keys of names in Arabic script by country
+	Settings      IndexSettings                      // Configuration the index was built with
+	BKTree        *util.BKTree                       // BK-tree over the name keys for fuzzy city name matching
+	SymSpell      *util.SymSpell                     // Symmetric delete dictionary over the name keys, used instead of the BK-tree
+	Localized     map[int]map[string]LocalizedName   // Map of geonameid to language to the best name in that language
 	mutex         sync.RWMutex                       // Mutex for thread-safe operations
+	names         map[string][]string                // Sorted names of the inverted index by country, built on first use
+	countries     []string                           // Sorted codes of the countries with indexed names, built on first use
+	namesMutex    sync.Mutex                         // Mutex for the sorted names and countries, which are built under the read lock
+}
+
+// Location is a point on the globe used to rank name lookup candidates
+type Location struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// MatchMode selects which candidate sources a name lookup may fall back to
+type MatchMode string
+
+const (
+	MatchExact    MatchMode = "exact"    // Only the name itself or its transliterated key
+	MatchFuzzy    MatchMode = "fuzzy"    // Exact, then names within a small edit distance
+	MatchPhonetic MatchMode = "phonetic" // Exact, then names that sound alike
+	MatchAuto     MatchMode = "auto"     // Exact, then fuzzy, then phonetic
+)
+
+// ParseMatchMode validates a match mode, an empty value selects MatchAuto
+func ParseMatchMode(value string) (MatchMode, error) {
+	switch mode := MatchMode(strings.ToLower(value)); mode {
+	case "":
+		return MatchAuto, nil
+	case MatchExact, MatchFuzzy, MatchPhonetic, MatchAuto:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown match mode %q", value)
+}
+
+// Query describes a name lookup together with optional ranking hints
+type Query struct {
+	Name        string
+	CountryCode string
+	Admin1Code  string    // Only consider places in this first-order administrative division
+	Admin2Code  string    // Only consider places in this second-order administrative division of Admin1Code
+	Match       MatchMode // Candidate sources to use, empty means MatchAuto
+	Near        *Location // Rank candidates by their distance to this point
+	RadiusKm    float64   // Discard candidates further than this from Near, zero means no limit
+}
+
+const (
+	maxFuzzyDistance      = 2 // Edit distance up to which fuzzy matches are accepted
+	maxSuggestionDistance = 3 // Edit distance up to which near misses are suggested
+)
+
+// Fuzzy matching backends of the name index
+const (
+	FuzzyBKTree   = "bktree"
+	FuzzySymSpell = "symspell"
+)
+
+// Suggestion is a known place name close to a name that was not found
+type Suggestion struct {
+	Name       string
+	Country    string
+	Distance   int   // Edit distance between the normalized keys of the two names
+	GeonameIDs []int // Places with the name in the country
 }
 
-// NewNameFinder creates a new NameFinder instance
+// LocalizedName is a name of a place in one language, ranked by how suitable it is for display
+type LocalizedName struct {
+	Name string
+	Rank int
+}
+
+// NewNameFinder creates a new NameFinder instance using the BK-tree for fuzzy matching
 func NewNameFinder() *Finder {
 	return &Finder{
 		InvertedIndex: make(map[string]map[string][]*city.City),
+		KeyIndex:      make(map[string]map[string][]*city.City),
+		PhoneticIndex: make(map[string]map[string][]*city.City),
+		SkeletonIndex: make(map[string]map[string][]*city.City),
+		AbjadIndex:    make(map[string]map[string][]*city.City),
+		Settings:      IndexSettings{FuzzyEngine: FuzzyBKTree},
 		BKTree:        util.NewBKTree(),
+		Localized:     make(map[int]map[string]LocalizedName),
 	}
 }
 
-// BuildIndex creates a name index from city data
-func BuildIndex(cities []city.SpatialCity) *Finder {
-	fmt.Printf("Building name index with %d cities\n", len(cities))
+// NewNameFinderWithEngine creates a new NameFinder instance using the given fuzzy matching backend,
+// an empty engine selects the BK-tree
+func NewNameFinderWithEngine(engine string) (*Finder, error) {
 	finder := NewNameFinder()
-	bar := pb.Full.Start(len(cities))
+	switch engine {
+	case "", FuzzyBKTree:
+	case FuzzySymSpell:
+		finder.Settings.FuzzyEngine = FuzzySymSpell
+		finder.BKTree = nil
+		finder.SymSpell = util.NewSymSpell(maxSuggestionDistance)
+	default:
+		return nil, fmt.Errorf("unknown fuzzy engine %q", engine)
+	}
+	return finder, nil
+}
+
+// BuildIndex creates a name index from city data and language-tagged alternate names
+func BuildIndex(cities []city.SpatialCity, alternateNames []dataLoader.AlternateName, settings IndexSettings) (*Finder, error) {
+	fmt.Printf("Building name index with %d cities\n", len(cities))
+	finder, err := NewNameFinderWithEngine(settings.FuzzyEngine)
+	if err != nil {
+		return nil, err
+	}
+	settings.FuzzyEngine = finder.Settings.FuzzyEngine
+	finder.Settings = settings
+	bar := pb.Full.Start(len(cities) + len(alternateNames))
 	for _, spatialCity := range cities {
 		finder.AddCity(spatialCity)
 		bar.Increment()
 	}
+	for _, alternateName := range alternateNames {
+		finder.AddAlternateName(alternateName)
+		bar.Increment()
+	}
 	bar.Finish()
-	return finder
+	return finder, nil
 }
 
 // AddCity adds a city to the NameFinder
@@ -45,35 +183,366 @@ func (nf *Finder) AddCity(spatialCity city.SpatialCity) {
 		nf.mutex.Lock()
 		if _, exists := nf.InvertedIndex[spatialCity.Country]; !exists {
 			nf.InvertedIndex[spatialCity.Country] = make(map[string][]*city.City)
+			nf.KeyIndex[spatialCity.Country] = make(map[string][]*city.City)
+			nf.PhoneticIndex[spatialCity.Country] = make(map[string][]*city.City)
+			nf.SkeletonIndex[spatialCity.Country] = make(map[string][]*city.City)
+			nf.AbjadIndex[spatialCity.Country] = make(map[string][]*city.City)
+			nf.namesMutex.Lock()
+			nf.countries = nil
+			nf.namesMutex.Unlock()
+		}
+		if _, exists := nf.InvertedIndex[spatialCity.Country][name]; !exists {
+			nf.namesMutex.Lock()
+			delete(nf.names, spatialCity.Country)
+			nf.namesMutex.Unlock()
 		}
 		nf.InvertedIndex[spatialCity.Country][name] = append(nf.InvertedIndex[spatialCity.Country][name], &spatialCity.City)
-		nf.BKTree.Add(name)
+		if key := Key(name, spatialCity.Country); key != "" {
+			// The fuzzy index is shared by all countries and skips the keys it already holds
+			if _, exists := nf.KeyIndex[spatialCity.Country][key]; !exists {
+				nf.fuzzy().Add(key)
+			}
+			nf.KeyIndex[spatialCity.Country][key] = append(nf.KeyIndex[spatialCity.Country][key], &spatialCity.City)
+			if tokenSet := tokenSetKey(key); tokenSet != key {
+				nf.KeyIndex[spatialCity.Country][tokenSet] = append(nf.KeyIndex[spatialCity.Country][tokenSet], &spatialCity.City)
+			}
+			if code := Phonetic(key); code != "" {
+				nf.PhoneticIndex[spatialCity.Country][code] = append(nf.PhoneticIndex[spatialCity.Country][code], &spatialCity.City)
+			}
+			if consonants := skeleton(key); consonants != "" {
+				skeletons := nf.SkeletonIndex[spatialCity.Country]
+				if isArabic(name) {
+					skeletons = nf.AbjadIndex[spatialCity.Country]
+				}
+				skeletons[consonants] = append(skeletons[consonants], &spatialCity.City)
+			}
+		}
 		nf.mutex.Unlock()
 	}
 }
 
+// AddAlternateName records a language-tagged name of a place, keeping the most suitable one per language.
+// Preferred names win over plain ones, which win over short names; colloquial and historic names come last.
+func (nf *Finder) AddAlternateName(alternateName dataLoader.AlternateName) {
+	rank := 2
+	switch {
+	case alternateName.Colloquial || alternateName.Historic:
+		rank = 0
+	case alternateName.Preferred:
+		rank = 3
+	case alternateName.Short:
+		rank = 1
+	}
+
+	nf.mutex.Lock()
+	defer nf.mutex.Unlock()
+
+	if _, exists := nf.Localized[alternateName.GeonameID]; !exists {
+		nf.Localized[alternateName.GeonameID] = make(map[string]LocalizedName)
+	}
+	language := strings.ToLower(alternateName.Language)
+	if current, exists := nf.Localized[alternateName.GeonameID][language]; exists && current.Rank >= rank {
+		return
+	}
+	nf.Localized[alternateName.GeonameID][language] = LocalizedName{Name: alternateName.Name, Rank: rank}
+}
+
+// LocalizedName returns the name of a place in the first of the given languages it is known in.
+// Regional tags such as "en-US" fall back to their base language.
+func (nf *Finder) LocalizedName(geonameID int, languages ...string) (string, bool) {
+	nf.mutex.RLock()
+	defer nf.mutex.RUnlock()
+
+	names := nf.Localized[geonameID]
+	if len(names) == 0 {
+		return "", false
+	}
+	for _, language := range languages {
+		language = strings.ToLower(language)
+		if localized, exists := names[language]; exists {
+			return localized.Name, true
+		}
+		if base, _, found := strings.Cut(language, "-"); found {
+			if localized, exists := names[base]; exists {
+				return localized.Name, true
+			}
+		}
+	}
+	return "", false
+}
+
 // CityByName finds the coordinates of a city by its name
 func (nf *Finder) CityByName(name string, countryCode string) *city.City {
+	cities := nf.Search(Query{Name: name, CountryCode: countryCode})
+	if len(cities) == 0 {
+		return nil
+	}
+	return cities[0]
+}
+
+// Search returns the cities matching the query, best match first.
+// Exact matches are preferred over matches of the normalized name key in any word order, which are preferred over
+// matches across Arabic script by consonants, fuzzy and then phonetic ones as far as the match mode allows. All of them are restricted to the requested admin division. When the query carries a location,
+// candidates are ordered by their distance to it and filtered by the radius.
+func (nf *Finder) Search(q Query) []*city.City {
 	nf.mutex.RLock()
 	defer nf.mutex.RUnlock()
 
-	if cities, exists := nf.InvertedIndex[countryCode][name]; exists && len(cities) > 0 {
-		return cities[0] // Return the first match if an exact match is found
+	if cities := q.rank(q.filter(unique(nf.InvertedIndex[q.CountryCode][q.Name]))); len(cities) > 0 {
+		return cities
 	}
 
-	// Perform fuzzy search using BK-tree if no exact match is found
-	candidates := nf.BKTree.Search(name, 2) // Adjust the distance threshold as needed
-	if len(candidates) > 0 {
-		for _, candidate := range candidates {
-			if cities, exists := nf.InvertedIndex[countryCode][candidate]; exists && len(cities) > 0 {
-				return cities[0]
-			}
+	key := Key(q.Name, q.CountryCode)
+	if cities := q.rank(q.filter(unique(nf.KeyIndex[q.CountryCode][key]))); len(cities) > 0 {
+		return cities
+	}
+	if cities := q.rank(q.filter(unique(nf.KeyIndex[q.CountryCode][tokenSetKey(key)]))); len(cities) > 0 {
+		return cities
+	}
+
+	if q.Match == MatchExact {
+		return nil
+	}
+
+	if q.Match != MatchPhonetic {
+		if cities := q.rank(q.filter(unique(nf.acrossAbjad(q.Name, key, q.CountryCode)))); len(cities) > 0 {
+			return cities
+		}
+
+		// Perform fuzzy search if no exact match is found, closest names first
+		var fuzzy []*city.City
+		for _, candidate := range byDistance(key, nf.fuzzy().Search(key, maxFuzzyDistance), maxFuzzyDistance) {
+			fuzzy = append(fuzzy, nf.KeyIndex[q.CountryCode][candidate]...)
+		}
+		if cities := q.rank(q.filter(unique(fuzzy))); len(cities) > 0 || q.Match == MatchFuzzy {
+			return cities
 		}
 	}
 
+	if code := Phonetic(key); code != "" {
+		return q.rank(q.filter(unique(nf.PhoneticIndex[q.CountryCode][code])))
+	}
 	return nil
 }
 
+// acrossAbjad returns the places whose names match a name written in the other script when one of them is Arabic:
+// Arabic names of places known by Latin names and the reverse. Arabic script omits short vowels, so the two only
+// share the consonant skeleton of their keys.
+func (nf *Finder) acrossAbjad(name, key, countryCode string) []*city.City {
+	consonants := skeleton(key)
+	if consonants == "" {
+		return nil
+	}
+	if isArabic(name) {
+		return nf.SkeletonIndex[countryCode][consonants]
+	}
+	return nf.AbjadIndex[countryCode][consonants]
+}
+
+// HasCountry reports whether any names are indexed in a country
+func (nf *Finder) HasCountry(countryCode string) bool {
+	nf.mutex.RLock()
+	defer nf.mutex.RUnlock()
+
+	_, exists := nf.InvertedIndex[countryCode]
+	return exists
+}
+
+// sortedCountries returns the codes of the countries with indexed names in alphabetical order, sorting them on first use.
+// It must be called under the read lock.
+func (nf *Finder) sortedCountries() []string {
+	nf.namesMutex.Lock()
+	defer nf.namesMutex.Unlock()
+
+	if nf.countries == nil {
+		nf.countries = make([]string, 0, len(nf.InvertedIndex))
+		for country := range nf.InvertedIndex {
+			nf.countries = append(nf.countries, country)
+		}
+		sort.Strings(nf.countries)
+	}
+	return nf.countries
+}
+
+// SearchAllCountries returns the places matching a name exactly, or by its key in any word order, in every country,
+// ordered by country. It serves lookups that nothing narrows down to a country, so the key is computed once
+// without the abbreviations of any country.
+func (nf *Finder) SearchAllCountries(name string) []*city.City {
+	nf.mutex.RLock()
+	defer nf.mutex.RUnlock()
+
+	key := Key(name, "")
+	tokenSet := tokenSetKey(key)
+	var cities []*city.City
+	for _, country := range nf.sortedCountries() {
+		found := nf.InvertedIndex[country][name]
+		if len(found) == 0 {
+			found = nf.KeyIndex[country][key]
+		}
+		if len(found) == 0 {
+			found = nf.KeyIndex[country][tokenSet]
+		}
+		cities = append(cities, unique(found)...)
+	}
+	return cities
+}
+
+// CountryByName returns the code of the country with the given name, in any of its indexed spellings.
+// Countries are recognized by their GeoNames political entity records (feature codes PCL*). A country whose
+// primary name matches is preferred. Otherwise a name shared by several countries, such as "Congo" or "Korea",
+// is ambiguous and yields no country.
+func (nf *Finder) CountryByName(countryName string) (string, bool) {
+	nf.mutex.RLock()
+	defer nf.mutex.RUnlock()
+
+	var byPrimaryName, byAnyName []string
+	for _, country := range nf.sortedCountries() {
+		key := Key(countryName, country)
+		for _, c := range nf.KeyIndex[country][key] {
+			if !strings.HasPrefix(c.FeatureCode, "PCL") {
+				continue
+			}
+			if Key(c.Name, country) == key && !slices.Contains(byPrimaryName, country) {
+				byPrimaryName = append(byPrimaryName, country)
+			}
+			if !slices.Contains(byAnyName, country) {
+				byAnyName = append(byAnyName, country)
+			}
+		}
+	}
+	switch {
+	case len(byPrimaryName) == 1:
+		return byPrimaryName[0], true
+	case len(byPrimaryName) == 0 && len(byAnyName) == 1:
+		return byAnyName[0], true
+	}
+	return "", false
+}
+
+// filter drops the candidates outside the requested administrative divisions
+func (q Query) filter(cities []*city.City) []*city.City {
+	if q.Admin1Code == "" && q.Admin2Code == "" {
+		return cities
+	}
+	filtered := cities[:0]
+	for _, c := range cities {
+		if (q.Admin1Code == "" || c.Admin1Code == q.Admin1Code) && (q.Admin2Code == "" || c.Admin2Code == q.Admin2Code) {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
+
+// Suggest returns up to limit place names close to the given name, nearest first.
+// Suggestions are restricted to the country unless the country code is empty.
+func (nf *Finder) Suggest(name, countryCode string, limit int) []Suggestion {
+	nf.mutex.RLock()
+	defer nf.mutex.RUnlock()
+
+	key := Key(name, countryCode)
+	type suggestionKey struct{ name, country string }
+	seen := make(map[suggestionKey]int) // Position of each suggestion, to keep its smallest distance
+	suggestions := make([]Suggestion, 0)
+	for _, candidate := range nf.fuzzy().Search(key, maxSuggestionDistance) {
+		distance, _ := util.LevenshteinWithin(key, candidate, maxSuggestionDistance)
+		for country, keys := range nf.KeyIndex {
+			if countryCode != "" && country != countryCode {
+				continue
+			}
+			for _, c := range keys[candidate] {
+				if i, exists := seen[suggestionKey{c.Name, c.Country}]; exists {
+					suggestions[i].Distance = min(suggestions[i].Distance, distance)
+					if !slices.Contains(suggestions[i].GeonameIDs, c.GeonameID) {
+						suggestions[i].GeonameIDs = append(suggestions[i].GeonameIDs, c.GeonameID)
+					}
+					continue
+				}
+				seen[suggestionKey{c.Name, c.Country}] = len(suggestions)
+				suggestions = append(suggestions, Suggestion{Name: c.Name, Country: c.Country, Distance: distance, GeonameIDs: []int{c.GeonameID}})
+			}
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Distance != suggestions[j].Distance {
+			return suggestions[i].Distance < suggestions[j].Distance
+		}
+		if suggestions[i].Name != suggestions[j].Name {
+			return suggestions[i].Name < suggestions[j].Name
+		}
+		return suggestions[i].Country < suggestions[j].Country
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// byDistance sorts fuzzy candidates by their edit distance to the key and then alphabetically,
+// so that the result does not depend on the order the fuzzy backend returns them in
+func byDistance(key string, candidates []string, maxDistance int) []string {
+	distances := make(map[string]int, len(candidates))
+	for _, candidate := range candidates {
+		distances[candidate], _ = util.LevenshteinWithin(key, candidate, maxDistance)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if distances[candidates[i]] != distances[candidates[j]] {
+			return distances[candidates[i]] < distances[candidates[j]]
+		}
+		return candidates[i] < candidates[j]
+	})
+	return candidates
+}
+
+// fuzzy returns the fuzzy matching backend in use
+func (nf *Finder) fuzzy() util.FuzzyIndex {
+	if nf.Settings.FuzzyEngine == FuzzySymSpell {
+		return nf.SymSpell
+	}
+	return nf.BKTree
+}
+
+// rank orders the candidates by distance to the query location and applies the radius
+func (q Query) rank(cities []*city.City) []*city.City {
+	if q.Near == nil || len(cities) == 0 {
+		return cities
+	}
+
+	distances := make(map[*city.City]float64, len(cities))
+	ranked := cities[:0]
+	for _, c := range cities {
+		distance := city.HaversineDistance(q.Near.Latitude, q.Near.Longitude, c.Latitude, c.Longitude)
+		if q.RadiusKm > 0 && distance > q.RadiusKm {
+			continue
+		}
+		distances[c] = distance
+		ranked = append(ranked, c)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return distances[ranked[i]] < distances[ranked[j]]
+	})
+	return ranked
+}
+
+// unique drops repeated entries of the same place, which occur when a city lists its own name among its alternate names
+func unique(cities []*city.City) []*city.City {
+	type placeKey struct {
+		name     string
+		lat, lon float64
+	}
+	seen := make(map[placeKey]bool, len(cities))
+	result := make([]*city.City, 0, len(cities))
+	for _, c := range cities {
+		key := placeKey{c.Name, c.Latitude, c.Longitude}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, c)
+	}
+	return result
+}
+
 // SerializeIndex saves the name index to a file
 func (nf *Finder) SerializeIndex(filepath string) error {
 	nf.mutex.Lock()
@@ -85,31 +554,103 @@ func (nf *Finder) SerializeIndex(filepath string) error {
 	}
 
 	encoder := gob.NewEncoder(file)
+	if err := encoder.Encode(IndexVersion); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := encoder.Encode(nf.Settings); err != nil {
+		_ = file.Close()
+		return err
+	}
 	if err := encoder.Encode(nf.InvertedIndex); err != nil {
 		_ = file.Close()
 		return err
 	}
-	if err := encoder.Encode(nf.BKTree); err != nil {
+	if err := encoder.Encode(nf.KeyIndex); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := encoder.Encode(nf.PhoneticIndex); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := encoder.Encode(nf.SkeletonIndex); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := encoder.Encode(nf.AbjadIndex); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := encoder.Encode(nf.fuzzy()); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := encoder.Encode(nf.Localized); err != nil {
 		_ = file.Close()
 		return err
 	}
 	return file.Close()
 }
 
-// DeserializeIndex loads the name index from a file
-func DeserializeIndex(filepath string) (*Finder, error) {
+// DeserializeIndex loads the name index from a file. Files of another format version, or built with other settings,
+// are rejected.
+func DeserializeIndex(filepath string, settings IndexSettings) (*Finder, error) {
 	file, err := os.Open(filepath)
 	if err != nil {
 		return nil, err
 	}
 
 	decoder := gob.NewDecoder(file)
+	var version int
+	if err := decoder.Decode(&version); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to read index version: %v", err)
+	}
+	if version != IndexVersion {
+		_ = file.Close()
+		return nil, fmt.Errorf("index format version %d, expected %d", version, IndexVersion)
+	}
 	finder := NewNameFinder()
+	if err := decoder.Decode(&finder.Settings); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to read index settings: %v", err)
+	}
+	if !finder.Settings.matches(settings) {
+		_ = file.Close()
+		return nil, fmt.Errorf("index built with settings %+v, expected %+v", finder.Settings, settings)
+	}
 	if err := decoder.Decode(&finder.InvertedIndex); err != nil {
 		_ = file.Close()
 		return nil, err
 	}
-	if err := decoder.Decode(&finder.BKTree); err != nil {
+	if err := decoder.Decode(&finder.KeyIndex); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if err := decoder.Decode(&finder.PhoneticIndex); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if err := decoder.Decode(&finder.SkeletonIndex); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if err := decoder.Decode(&finder.AbjadIndex); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if finder.Settings.FuzzyEngine == FuzzySymSpell {
+		finder.BKTree = nil
+		err = decoder.Decode(&finder.SymSpell)
+	} else {
+		err = decoder.Decode(&finder.BKTree)
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if err := decoder.Decode(&finder.Localized); err != nil {
 		_ = file.Close()
 		return nil, err
 	}
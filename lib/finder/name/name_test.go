@@ -1,6 +1,7 @@
 package name
 
 import (
+	"encoding/gob"
 	"github.com/SamyRai/cityFinder/lib/city"
 	"github.com/stretchr/testify/assert"
 	"os"
@@ -28,7 +29,7 @@ func TestFinder_SerializeDeserialize(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Deserialize the finder from the file
-	deserializedFinder, err := DeserializeIndex(tmpfile.Name())
+	deserializedFinder, err := DeserializeIndex(tmpfile.Name(), IndexSettings{})
 	assert.NoError(t, err)
 
 	// Compare the original and deserialized finders
@@ -36,3 +37,233 @@ func TestFinder_SerializeDeserialize(t *testing.T) {
 	assert.NotNil(t, deserializedFinder.BKTree)
 	assert.Equal(t, finder.BKTree.Root.Term, deserializedFinder.BKTree.Root.Term)
 }
+
+func TestDeserializeIndex_OtherVersion(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "test_name_finder_*.gob")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.Remove(tmpfile.Name())
+	}()
+
+	// Indexes written before the format was versioned start with the inverted index
+	finder := NewNameFinder()
+	assert.NoError(t, gob.NewEncoder(tmpfile).Encode(finder.InvertedIndex))
+	assert.NoError(t, tmpfile.Close())
+	_, err = DeserializeIndex(tmpfile.Name(), IndexSettings{})
+	assert.Error(t, err)
+
+	file, err := os.Create(tmpfile.Name())
+	assert.NoError(t, err)
+	assert.NoError(t, gob.NewEncoder(file).Encode(IndexVersion+1))
+	assert.NoError(t, file.Close())
+	_, err = DeserializeIndex(tmpfile.Name(), IndexSettings{})
+	assert.ErrorContains(t, err, "index format version")
+}
+
+func TestDeserializeIndex_OtherSettings(t *testing.T) {
+	settings := IndexSettings{AlternateNamesFile: "alternateNamesV2.txt", Languages: []string{"en", "DE"}}
+	finder, err := BuildIndex(nil, nil, settings)
+	assert.NoError(t, err)
+
+	tmpfile, err := os.CreateTemp("", "test_name_finder_*.gob")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.Remove(tmpfile.Name())
+	}()
+	assert.NoError(t, finder.SerializeIndex(tmpfile.Name()))
+
+	// The order and case of the languages do not matter
+	deserializedFinder, err := DeserializeIndex(tmpfile.Name(), IndexSettings{FuzzyEngine: FuzzyBKTree, AlternateNamesFile: "alternateNamesV2.txt", Languages: []string{"de", "en"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "alternateNamesV2.txt", deserializedFinder.Settings.AlternateNamesFile)
+
+	// Adding a language or dropping the alternate names rebuilds the index
+	for _, other := range []IndexSettings{
+		{AlternateNamesFile: "alternateNamesV2.txt", Languages: []string{"de", "en", "fr"}},
+		{AlternateNamesFile: "alternateNamesV2.txt"},
+		{Languages: []string{"de", "en"}},
+	} {
+		_, err = DeserializeIndex(tmpfile.Name(), other)
+		assert.ErrorContains(t, err, "settings", other)
+	}
+}
+
+func TestFinder_SearchNear(t *testing.T) {
+	finder := NewNameFinder()
+	finder.AddCity(city.SpatialCity{City: city.City{Name: "Springfield", Country: "US", Latitude: 39.7817, Longitude: -89.6501}})
+	finder.AddCity(city.SpatialCity{City: city.City{Name: "Springfield", Country: "US", Latitude: 42.1015, Longitude: -72.5898}})
+
+	// Near Boston the Massachusetts Springfield comes first
+	cities := finder.Search(Query{Name: "Springfield", CountryCode: "US", Near: &Location{Latitude: 42.3601, Longitude: -71.0589}})
+	assert.Len(t, cities, 2)
+	assert.Equal(t, 42.1015, cities[0].Latitude)
+
+	// Near Chicago the Illinois one does
+	cities = finder.Search(Query{Name: "Springfield", CountryCode: "US", Near: &Location{Latitude: 41.8781, Longitude: -87.6298}})
+	assert.Len(t, cities, 2)
+	assert.Equal(t, 39.7817, cities[0].Latitude)
+
+	// The radius drops candidates that are too far away, fuzzy matches included
+	cities = finder.Search(Query{Name: "Springfeld", CountryCode: "US", Near: &Location{Latitude: 41.8781, Longitude: -87.6298}, RadiusKm: 500})
+	assert.Len(t, cities, 1)
+	assert.Equal(t, 39.7817, cities[0].Latitude)
+}
+
+func TestFinder_SearchFuzzyClosestFirst(t *testing.T) {
+	for _, engine := range []string{FuzzyBKTree, FuzzySymSpell} {
+		finder, err := NewNameFinderWithEngine(engine)
+		assert.NoError(t, err)
+		// Added so that the farther names come first in the fuzzy backends
+		finder.AddCity(city.SpatialCity{City: city.City{Name: "Marlin", Country: "DE"}})
+		finder.AddCity(city.SpatialCity{City: city.City{Name: "Berlin", Country: "DE"}})
+		finder.AddCity(city.SpatialCity{City: city.City{Name: "Merlin", Country: "DE"}})
+
+		// Merlin is one edit away, Berlin and Marlin two
+		cities := finder.Search(Query{Name: "Merlen", CountryCode: "DE"})
+		names := make([]string, len(cities))
+		for i, c := range cities {
+			names[i] = c.Name
+		}
+		assert.Equal(t, []string{"Merlin", "Berlin", "Marlin"}, names, engine)
+		assert.Equal(t, "Merlin", finder.CityByName("Merlen", "DE").Name, engine)
+	}
+}
+
+func TestFinder_SearchMatchModes(t *testing.T) {
+	finder := NewNameFinder()
+	finder.AddCity(city.SpatialCity{City: city.City{Name: "Stuttgart", Country: "DE", Latitude: 48.7823, Longitude: 9.177}})
+
+	assert.Equal(t, Phonetic(Key("Stuttgart", "DE")), Phonetic(Key("Schtuttgard", "DE")))
+
+	// Too far from "stuttgart" for the edit distance, but it sounds the same
+	assert.Empty(t, finder.Search(Query{Name: "Schtuttgard", CountryCode: "DE", Match: MatchFuzzy}))
+	assert.Len(t, finder.Search(Query{Name: "Schtuttgard", CountryCode: "DE", Match: MatchPhonetic}), 1)
+	assert.Len(t, finder.Search(Query{Name: "Schtuttgard", CountryCode: "DE"}), 1)
+
+	// Spelling variants within the edit distance are not exact matches
+	assert.Empty(t, finder.Search(Query{Name: "Shtutgart", CountryCode: "DE", Match: MatchExact}))
+	assert.Len(t, finder.Search(Query{Name: "Shtutgart", CountryCode: "DE", Match: MatchFuzzy}), 1)
+	assert.Len(t, finder.Search(Query{Name: "stuttgart", CountryCode: "DE", Match: MatchExact}), 1)
+}
+
+func TestFinder_SearchNormalized(t *testing.T) {
+	finder := NewNameFinder()
+	finder.AddCity(city.SpatialCity{City: city.City{Name: "Saint Petersburg", Country: "RU", AltNames: []string{"Sankt-Peterburg"}}})
+	finder.AddCity(city.SpatialCity{City: city.City{Name: "Frankfurt am Main", Country: "DE"}})
+	finder.AddCity(city.SpatialCity{City: city.City{Name: "Mount Vernon", Country: "US"}})
+	finder.AddCity(city.SpatialCity{City: city.City{Name: "L'Aquila", Country: "IT"}})
+	finder.AddCity(city.SpatialCity{City: city.City{Name: "Neustadt bei Coburg", Country: "DE"}})
+
+	cases := []struct{ query, country, expected string }{
+		{"St. Petersburg", "RU", "Saint Petersburg"},
+		{"Sankt Petersburg", "RU", "Saint Petersburg"},
+		{"St Peterburg", "RU", "Saint Petersburg"},
+		{"Frankfurt a. M.", "DE", "Frankfurt am Main"},
+		{"Frankfurt a.M.", "DE", "Frankfurt am Main"},
+		{"Main, Frankfurt am", "DE", "Frankfurt am Main"},
+		{"Mt. Vernon", "US", "Mount Vernon"},
+		{"LAquila", "IT", "L'Aquila"},
+		{"L’Aquila", "IT", "L'Aquila"},
+		{"Neustadt b. Coburg", "DE", "Neustadt bei Coburg"},
+	}
+	for _, tc := range cases {
+		found := finder.CityByName(tc.query, tc.country)
+		if assert.NotNil(t, found, tc.query) {
+			assert.Equal(t, tc.expected, found.Name, tc.query)
+		}
+	}
+
+	// Abbreviations only expand for the languages of the country
+	assert.Equal(t, "mount vernon", Key("Mt. Vernon", "US"))
+	assert.Equal(t, "mont vernon", Key("Mt. Vernon", "FR"))
+}
+
+func TestKey_Prepositions(t *testing.T) {
+	assert.Equal(t, "neustadt bei coburg", Key("Neustadt b. Coburg", "DE"))
+	assert.Equal(t, "weiden im der oberpfalz", Key("Weiden i. der Oberpfalz", "DE"))
+	// Lone letters are names, not prepositions
+	assert.Equal(t, "b", Key("B", "DE"))
+	assert.Equal(t, "i", Key("I", "DE"))
+	assert.Equal(t, "b 3", Key("B 3", "DE"))
+}
+
+func TestFinder_SymSpellEngine(t *testing.T) {
+	finder, err := NewNameFinderWithEngine(FuzzySymSpell)
+	assert.NoError(t, err)
+	finder.AddCity(city.SpatialCity{City: city.City{Name: "Stuttgart", Country: "DE"}})
+	assert.NotNil(t, finder.CityByName("Stutgart", "DE"))
+
+	tmpfile, err := os.CreateTemp("", "test_name_finder_*.gob")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.Remove(tmpfile.Name())
+	}()
+	assert.NoError(t, finder.SerializeIndex(tmpfile.Name()))
+
+	deserializedFinder, err := DeserializeIndex(tmpfile.Name(), IndexSettings{FuzzyEngine: FuzzySymSpell})
+	assert.NoError(t, err)
+	assert.Equal(t, FuzzySymSpell, deserializedFinder.Settings.FuzzyEngine)
+	assert.Nil(t, deserializedFinder.BKTree)
+	assert.NotNil(t, deserializedFinder.CityByName("Stutgart", "DE"))
+
+	// Switching the engine in the configuration rebuilds the index
+	_, err = DeserializeIndex(tmpfile.Name(), IndexSettings{FuzzyEngine: FuzzyBKTree})
+	assert.ErrorContains(t, err, "settings")
+	_, err = DeserializeIndex(tmpfile.Name(), IndexSettings{})
+	assert.ErrorContains(t, err, "settings")
+
+	_, err = NewNameFinderWithEngine("trigram")
+	assert.Error(t, err)
+}
+
+func TestFinder_CountryByName(t *testing.T) {
+	finder := NewNameFinder()
+	countries := []city.City{
+		{Name: "Republic of the Congo", Country: "CG", FeatureCode: "PCLI", AltNames: []string{"Congo", "Congo-Brazzaville"}},
+		{Name: "Democratic Republic of the Congo", Country: "CD", FeatureCode: "PCLI", AltNames: []string{"Congo", "Congo-Kinshasa"}},
+		{Name: "Guinea", Country: "GN", FeatureCode: "PCLI"},
+		{Name: "Equatorial Guinea", Country: "GQ", FeatureCode: "PCLI", AltNames: []string{"Guinea"}},
+		{Name: "Germany", Country: "DE", FeatureCode: "PCLI", AltNames: []string{"Deutschland"}},
+		{Name: "Germany", Country: "US", FeatureCode: "PPL"},
+	}
+	for _, c := range countries {
+		finder.AddCity(city.SpatialCity{City: c})
+	}
+
+	for i := 0; i < 10; i++ {
+		_, ok := finder.CountryByName("Congo")
+		assert.False(t, ok, "names shared by several countries are ambiguous")
+	}
+	cases := map[string]string{
+		"Congo-Kinshasa": "CD",
+		"Guinea":         "GN",
+		"Deutschland":    "DE",
+		"germany":        "DE",
+	}
+	for countryName, expected := range cases {
+		country, ok := finder.CountryByName(countryName)
+		assert.True(t, ok, countryName)
+		assert.Equal(t, expected, country, countryName)
+	}
+}
+
+func TestFinder_SearchAllCountries(t *testing.T) {
+	finder := NewNameFinder()
+	finder.AddCity(city.SpatialCity{City: city.City{Name: "Paris", Country: "US", Latitude: 33.6609}})
+	finder.AddCity(city.SpatialCity{City: city.City{Name: "Paris", Country: "FR", Latitude: 48.8534}})
+	finder.AddCity(city.SpatialCity{City: city.City{Name: "Pariz", Country: "HR"}})
+	finder.AddCity(city.SpatialCity{City: city.City{Name: "Saint-Denis", Country: "FR"}})
+
+	cities := finder.SearchAllCountries("paris")
+	if assert.Len(t, cities, 2, "only exact matches") {
+		assert.Equal(t, "FR", cities[0].Country)
+		assert.Equal(t, "US", cities[1].Country)
+	}
+	assert.Len(t, finder.SearchAllCountries("Denis Saint"), 1)
+
+	assert.True(t, finder.HasCountry("HR"))
+	assert.False(t, finder.HasCountry("DE"))
+	finder.AddCity(city.SpatialCity{City: city.City{Name: "Paris", Country: "DE"}})
+	assert.True(t, finder.HasCountry("DE"))
+	assert.Len(t, finder.SearchAllCountries("Paris"), 3, "countries added later are searched too")
+}
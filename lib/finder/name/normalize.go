@@ -0,0 +1,120 @@
+package name
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// abbreviations expand abbreviated tokens per language. The "" dictionary applies to every country and maps
+// the many spellings of "saint" onto one token, so "St. Petersburg" and "Sankt-Peterburg" share their first token.
+// Keys may span two tokens, e.g. "a m" as left over from "a.M." after the punctuation is removed.
+var abbreviations = map[string]map[string]string{
+	"": {
+		"st": "saint", "sankt": "saint", "ste": "sainte",
+	},
+	"de": {
+		"a m": "am main", "a d": "an der", "bd": "bad", "str": "strasse",
+	},
+	"en": {
+		"mt": "mount", "ft": "fort",
+	},
+	"fr": {
+		"mt": "mont", "ft": "fort",
+	},
+	"es": {
+		"sta": "santa", "sto": "santo", "sn": "san",
+	},
+	"it": {
+		"s": "san", "sta": "santa", "sto": "santo",
+	},
+	"pt": {
+		"sta": "santa", "sto": "santo", "s": "sao",
+	},
+	"nl": {
+		"sint": "saint",
+	},
+}
+
+// prepositions expand abbreviated prepositions per language. Unlike other abbreviations they only apply after
+// the first token, so "Neustadt b. Coburg" reads "bei" while a place called "B" keeps its name.
+var prepositions = map[string]map[string]string{
+	"de": {
+		"i": "im", "b": "bei",
+	},
+}
+
+// countryLanguages lists the languages whose abbreviations apply to the names of a country, in order of precedence
+var countryLanguages = map[string][]string{
+	"AT": {"de"}, "CH": {"de", "fr", "it"}, "DE": {"de"}, "LI": {"de"}, "LU": {"fr", "de"},
+	"AU": {"en"}, "GB": {"en"}, "IE": {"en"}, "NZ": {"en"}, "US": {"en"}, "ZA": {"en"}, "CA": {"en", "fr"},
+	"BE": {"nl", "fr"}, "FR": {"fr"}, "MC": {"fr"}, "NL": {"nl"},
+	"AR": {"es"}, "CL": {"es"}, "CO": {"es"}, "ES": {"es"}, "MX": {"es"}, "PE": {"es"}, "VE": {"es"},
+	"IT": {"it"}, "SM": {"it"}, "VA": {"it"},
+	"BR": {"pt"}, "PT": {"pt"},
+}
+
+// apostrophes are removed rather than treated as separators, so "L'Aquila" and "LAquila" share a key
+var apostrophes = strings.NewReplacer("'", "", "’", "", "‘", "", "ʼ", "", "`", "", "´", "")
+
+// Key returns the canonical key of a name, used to match names regardless of script, case, diacritics
+// and abbreviations. The country selects the abbreviation dictionaries that apply.
+// Cyrillic, Greek, Arabic, kana and Hangul are transliterated, but no other CJK script. Han characters have no
+// rule-based reading, as it differs between Chinese and Japanese, and are kept as is, so Chinese and kanji names
+// only match names in Han script.
+func Key(name, countryCode string) string {
+	latinName := transliterate(strings.ToLower(apostrophes.Replace(name)))
+	tokens := strings.FieldsFunc(latinName, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(expandAbbreviations(tokens, countryCode), " ")
+}
+
+// tokenSetKey orders the tokens of a key, so that "Main, Frankfurt am" and "Frankfurt am Main" share it
+func tokenSetKey(key string) string {
+	tokens := strings.Fields(key)
+	sort.Strings(tokens)
+	return strings.Join(tokens, " ")
+}
+
+// expandAbbreviations replaces abbreviated tokens, trying two-token abbreviations before single ones
+func expandAbbreviations(tokens []string, countryCode string) []string {
+	dictionaries := []map[string]string{abbreviations[""]}
+	var prepositionDictionaries []map[string]string
+	for _, language := range countryLanguages[countryCode] {
+		dictionaries = append(dictionaries, abbreviations[language])
+		prepositionDictionaries = append(prepositionDictionaries, prepositions[language])
+	}
+
+	expanded := make([]string, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		if i+1 < len(tokens) {
+			if expansion, found := lookupAbbreviation(dictionaries, tokens[i]+" "+tokens[i+1]); found {
+				expanded = append(expanded, strings.Fields(expansion)...)
+				i++
+				continue
+			}
+		}
+		if expansion, found := lookupAbbreviation(dictionaries, tokens[i]); found {
+			expanded = append(expanded, strings.Fields(expansion)...)
+			continue
+		}
+		if i > 0 {
+			if expansion, found := lookupAbbreviation(prepositionDictionaries, tokens[i]); found {
+				expanded = append(expanded, expansion)
+				continue
+			}
+		}
+		expanded = append(expanded, tokens[i])
+	}
+	return expanded
+}
+
+func lookupAbbreviation(dictionaries []map[string]string, token string) (string, bool) {
+	for _, dictionary := range dictionaries {
+		if expansion, found := dictionary[token]; found {
+			return expansion, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,155 @@
+package name
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/SamyRai/cityFinder/lib/city"
+)
+
+const (
+	maxPatternLength = 256  // Longest pattern accepted, longer ones are rejected before compiling
+	checkInterval    = 1024 // Number of names scanned between two checks for cancellation
+)
+
+// PatternQuery describes a scan of all place names against a glob pattern or a regular expression
+type PatternQuery struct {
+	Pattern      string
+	Regexp       bool   // Read the pattern as an RE2 regular expression instead of a glob
+	CountryCode  string // Only scan the names of this country, empty scans every country
+	FeatureClass string // Only return places of this GeoNames feature class, e.g. "P" for populated places
+	Limit        int    // Stop after this many matches, zero means no limit
+}
+
+// PatternMatch is a place whose name or one of whose alternate names matched a pattern
+type PatternMatch struct {
+	MatchedName string
+	City        *city.City
+}
+
+// PatternResult holds the matches of a pattern search, sorted by country, matched name and geonameid.
+// Truncated results are the first matches in that order.
+type PatternResult struct {
+	Matches   []PatternMatch
+	Truncated bool // The limit was reached before all names were scanned
+}
+
+// CompilePattern turns a glob or an RE2 regular expression into a matcher.
+// Globs match the whole name regardless of case, "*" stands for any run of characters and "?" for a single one.
+func CompilePattern(pattern string, isRegexp bool) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("pattern is empty")
+	}
+	if len(pattern) > maxPatternLength {
+		return nil, fmt.Errorf("pattern is longer than %d bytes", maxPatternLength)
+	}
+	if isRegexp {
+		return regexp.Compile(pattern)
+	}
+
+	var b strings.Builder
+	b.WriteString("(?i)^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// SearchPattern scans the place names, including alternate names, for matches of a glob or regular expression.
+// The scan stops at the limit or when the context is done, in which case the context error is returned.
+func (nf *Finder) SearchPattern(ctx context.Context, q PatternQuery) (PatternResult, error) {
+	matcher, err := CompilePattern(q.Pattern, q.Regexp)
+	if err != nil {
+		return PatternResult{}, err
+	}
+
+	nf.mutex.RLock()
+	defer nf.mutex.RUnlock()
+
+	countries := make([]string, 0, len(nf.InvertedIndex))
+	for country := range nf.InvertedIndex {
+		if q.CountryCode == "" || country == q.CountryCode {
+			countries = append(countries, country)
+		}
+	}
+	sort.Strings(countries)
+
+	type matchKey struct {
+		name      string
+		geonameID int
+	}
+	seen := make(map[matchKey]bool)
+	var result PatternResult
+	scanned := 0
+	for _, country := range countries {
+		// Names are scanned in order, so that a truncated result is the same on every call
+		for _, placeName := range nf.sortedNames(country) {
+			if scanned++; scanned%checkInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return PatternResult{}, err
+				}
+			}
+			if !matcher.MatchString(placeName) {
+				continue
+			}
+			cities := slices.Clone(nf.InvertedIndex[country][placeName])
+			sort.Slice(cities, func(i, j int) bool {
+				return cities[i].GeonameID < cities[j].GeonameID
+			})
+			for _, c := range cities {
+				if q.FeatureClass != "" && c.FeatureClass != q.FeatureClass {
+					continue
+				}
+				if seen[matchKey{placeName, c.GeonameID}] {
+					continue
+				}
+				seen[matchKey{placeName, c.GeonameID}] = true
+				if q.Limit > 0 && len(result.Matches) == q.Limit {
+					result.Truncated = true
+					break
+				}
+				result.Matches = append(result.Matches, PatternMatch{MatchedName: placeName, City: c})
+			}
+			if result.Truncated {
+				break
+			}
+		}
+		if result.Truncated {
+			break
+		}
+	}
+	return result, nil
+}
+
+// sortedNames returns the names of a country in the inverted index in alphabetical order. They are sorted on the
+// first scan of the country and kept until a name is added to it. The caller holds the read lock.
+func (nf *Finder) sortedNames(country string) []string {
+	nf.namesMutex.Lock()
+	defer nf.namesMutex.Unlock()
+
+	if names, exists := nf.names[country]; exists {
+		return names
+	}
+	names := make([]string, 0, len(nf.InvertedIndex[country]))
+	for placeName := range nf.InvertedIndex[country] {
+		names = append(names, placeName)
+	}
+	sort.Strings(names)
+	if nf.names == nil {
+		nf.names = make(map[string][]string)
+	}
+	nf.names[country] = names
+	return names
+}
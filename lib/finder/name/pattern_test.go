@@ -0,0 +1,91 @@
+package name
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SamyRai/cityFinder/lib/city"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func patternFinder() *Finder {
+	finder := NewNameFinder()
+	finder.AddCity(city.SpatialCity{City: city.City{GeonameID: 1, Name: "San Francisco", Country: "US", FeatureClass: "P"}})
+	finder.AddCity(city.SpatialCity{City: city.City{GeonameID: 2, Name: "San Jose", Country: "US", FeatureClass: "P", AltNames: []string{"San José"}}})
+	finder.AddCity(city.SpatialCity{City: city.City{GeonameID: 3, Name: "San Andreas Fault", Country: "US", FeatureClass: "T"}})
+	finder.AddCity(city.SpatialCity{City: city.City{GeonameID: 4, Name: "Hamburg", Country: "DE", FeatureClass: "P"}})
+	finder.AddCity(city.SpatialCity{City: city.City{GeonameID: 5, Name: "Pittsburgh", Country: "US", FeatureClass: "P"}})
+	return finder
+}
+
+func matchedNames(result PatternResult) []string {
+	names := make([]string, len(result.Matches))
+	for i, match := range result.Matches {
+		names[i] = match.MatchedName
+	}
+	return names
+}
+
+func TestFinder_SearchPattern(t *testing.T) {
+	finder := patternFinder()
+
+	result, err := finder.SearchPattern(context.Background(), PatternQuery{Pattern: "san *"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"San Andreas Fault", "San Francisco", "San Jose", "San José"}, matchedNames(result))
+	assert.False(t, result.Truncated)
+
+	result, err = finder.SearchPattern(context.Background(), PatternQuery{Pattern: "San *", FeatureClass: "P"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"San Francisco", "San Jose", "San José"}, matchedNames(result))
+
+	result, err = finder.SearchPattern(context.Background(), PatternQuery{Pattern: "*burg", CountryCode: "DE"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Hamburg"}, matchedNames(result))
+
+	result, err = finder.SearchPattern(context.Background(), PatternQuery{Pattern: `burgh?$`, Regexp: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Hamburg", "Pittsburgh"}, matchedNames(result))
+
+	result, err = finder.SearchPattern(context.Background(), PatternQuery{Pattern: "San*", Limit: 2})
+	require.NoError(t, err)
+	assert.Len(t, result.Matches, 2)
+	assert.True(t, result.Truncated)
+
+	_, err = finder.SearchPattern(context.Background(), PatternQuery{Pattern: "San (", Regexp: true})
+	assert.Error(t, err)
+}
+
+func TestFinder_SearchPatternTruncatedInOrder(t *testing.T) {
+	finder := NewNameFinder()
+	for i, placeName := range []string{"Bergen", "Berlin", "Bern", "Bernau", "Bernburg", "Bergheim", "Berching", "Bernkastel"} {
+		finder.AddCity(city.SpatialCity{City: city.City{GeonameID: 100 - i, Name: placeName, Country: "DE"}})
+	}
+	finder.AddCity(city.SpatialCity{City: city.City{GeonameID: 7, Name: "Bern", Country: "DE"}})
+
+	for i := 0; i < 20; i++ {
+		result, err := finder.SearchPattern(context.Background(), PatternQuery{Pattern: "Ber*", Limit: 5})
+		require.NoError(t, err)
+		assert.True(t, result.Truncated)
+		assert.Equal(t, []string{"Berching", "Bergen", "Bergheim", "Berlin", "Bern"}, matchedNames(result))
+		assert.Equal(t, 7, result.Matches[4].City.GeonameID)
+	}
+
+	// Names added after a scan are picked up by the next one
+	finder.AddCity(city.SpatialCity{City: city.City{GeonameID: 1, Name: "Beratzhausen", Country: "DE"}})
+	result, err := finder.SearchPattern(context.Background(), PatternQuery{Pattern: "Ber*", Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Beratzhausen"}, matchedNames(result))
+}
+
+func TestFinder_SearchPatternCancelled(t *testing.T) {
+	finder := NewNameFinder()
+	for i := 0; i < 2*checkInterval; i++ {
+		finder.AddCity(city.SpatialCity{City: city.City{GeonameID: i, Name: string(rune('A'+i%26)) + string(rune('a'+i/26%26)) + string(rune('a'+i/676)), Country: "US"}})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := finder.SearchPattern(ctx, PatternQuery{Pattern: "*"})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,89 @@
+package name
+
+import "strings"
+
+// Phonetic returns the Cologne phonetics (Kölner Phonetik) code of a name key, word by word.
+// Names that sound alike in German share a code, e.g. "Stuttgart" and "Schtuttgard" both become "822472".
+func Phonetic(key string) string {
+	words := strings.Fields(key)
+	codes := make([]string, 0, len(words))
+	for _, word := range words {
+		if code := colognePhonetic(word); code != "" {
+			codes = append(codes, code)
+		}
+	}
+	return strings.Join(codes, " ")
+}
+
+// colognePhonetic encodes a single lower-case ASCII word
+func colognePhonetic(word string) string {
+	var raw []byte
+	for i := 0; i < len(word); i++ {
+		var prev, next byte
+		if i > 0 {
+			prev = word[i-1]
+		}
+		if i+1 < len(word) {
+			next = word[i+1]
+		}
+
+		switch c := word[i]; c {
+		case 'a', 'e', 'i', 'j', 'o', 'u', 'y':
+			raw = append(raw, '0')
+		case 'b':
+			raw = append(raw, '1')
+		case 'p':
+			if next == 'h' {
+				raw = append(raw, '3')
+			} else {
+				raw = append(raw, '1')
+			}
+		case 'd', 't':
+			if strings.IndexByte("csz", next) >= 0 {
+				raw = append(raw, '8')
+			} else {
+				raw = append(raw, '2')
+			}
+		case 'f', 'v', 'w':
+			raw = append(raw, '3')
+		case 'g', 'k', 'q':
+			raw = append(raw, '4')
+		case 'c':
+			switch {
+			case i == 0 && strings.IndexByte("ahkloqrux", next) >= 0:
+				raw = append(raw, '4')
+			case i > 0 && strings.IndexByte("sz", prev) < 0 && strings.IndexByte("ahkoqux", next) >= 0:
+				raw = append(raw, '4')
+			default:
+				raw = append(raw, '8')
+			}
+		case 'x':
+			if strings.IndexByte("ckq", prev) >= 0 {
+				raw = append(raw, '8')
+			} else {
+				raw = append(raw, '4', '8')
+			}
+		case 'l':
+			raw = append(raw, '5')
+		case 'm', 'n':
+			raw = append(raw, '6')
+		case 'r':
+			raw = append(raw, '7')
+		case 's', 'z':
+			raw = append(raw, '8')
+		}
+	}
+
+	// Collapse repeated codes, then drop the vowels except at the start
+	code := make([]byte, 0, len(raw))
+	for i, c := range raw {
+		if i > 0 && raw[i-1] == c {
+			continue
+		}
+		if c == '0' && len(code) > 0 {
+			continue
+		}
+		code = append(code, c)
+	}
+	return string(code)
+}
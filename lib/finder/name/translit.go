@@ -0,0 +1,229 @@
+package name
+
+import (
+	"strings"
+	"unicode"
+)
+
+// latinBase groups accented Latin letters by the ASCII letters they fold to
+var latinBase = map[string]string{
+	"a":  "àáâãäåāăąǎǟǡǻȁȃȧḁạảấầẩẫậắằẳẵặ",
+	"ae": "æǽ",
+	"b":  "ḃḅḇ",
+	"c":  "çćĉċčḉ",
+	"d":  "ďđðḋḍḏḑḓ",
+	"e":  "èéêëēĕėęěȅȇȩḕḗḙḛḝẹẻẽếềểễệ",
+	"f":  "ḟ",
+	"g":  "ĝğġģǧǵḡ",
+	"h":  "ĥħȟḣḥḧḩḫẖ",
+	"i":  "ìíîïĩīĭįıǐȉȋḭḯỉị",
+	"j":  "ĵǰ",
+	"k":  "ķǩḱḳḵ",
+	"l":  "ĺļľŀłḷḹḻḽ",
+	"m":  "ḿṁṃ",
+	"n":  "ñńņňǹṅṇṉṋ",
+	"ng": "ŋ",
+	"o":  "òóôõöøōŏőơǒǫǭǿȍȏȫȭȯȱṍṏṑṓọỏốồổỗộớờởỡợ",
+	"oe": "œ",
+	"p":  "ṕṗ",
+	"r":  "ŕŗřȑȓṙṛṝṟ",
+	"s":  "śŝşšșſṡṣṥṧṩ",
+	"ss": "ß",
+	"t":  "ţťŧțṫṭṯṱẗ",
+	"th": "þ",
+	"u":  "ùúûüũūŭůűųưǔǖǘǚǜȕȗṳṵṷṹṻụủứừửữự",
+	"v":  "ṽṿ",
+	"w":  "ŵẁẃẅẇẉẘ",
+	"x":  "ẋẍ",
+	"y":  "ýÿŷȳẏẙỳỵỷỹ",
+	"z":  "źżžẑẓẕ",
+}
+
+// cyrillic covers Russian, Ukrainian, Belarusian, Bulgarian, Serbian and Macedonian letters (BGN/PCGN, simplified)
+var cyrillic = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g", 'ў': "u", 'ђ': "dj", 'ј': "j",
+	'љ': "lj", 'њ': "nj", 'ћ': "c", 'џ': "dz", 'ѓ': "gj", 'ќ': "kj", 'ѕ': "dz",
+}
+
+// greek covers the modern Greek alphabet including accented vowels (ELOT 743, simplified)
+var greek = map[rune]string{
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th",
+	'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p",
+	'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps",
+	'ω': "o", 'ά': "a", 'έ': "e", 'ή': "i", 'ί': "i", 'ϊ': "i", 'ΐ': "i", 'ό': "o",
+	'ύ': "y", 'ϋ': "y", 'ΰ': "y", 'ώ': "o",
+}
+
+// arabic maps Arabic and Persian letters to the consonant skeleton GeoNames uses for romanized Arabic names.
+// Short vowels are not written in Arabic script, so the harakat are dropped.
+var arabic = map[rune]string{
+	'ا': "a", 'أ': "a", 'إ': "i", 'آ': "a", 'ٱ': "a", 'ب': "b", 'ت': "t", 'ث': "th",
+	'ج': "j", 'ح': "h", 'خ': "kh", 'د': "d", 'ذ': "dh", 'ر': "r", 'ز': "z", 'س': "s",
+	'ش': "sh", 'ص': "s", 'ض': "d", 'ط': "t", 'ظ': "z", 'ع': "", 'غ': "gh", 'ف': "f",
+	'ق': "q", 'ك': "k", 'ل': "l", 'م': "m", 'ن': "n", 'ه': "h", 'ة': "h", 'و': "w",
+	'ي': "y", 'ى': "a", 'ء': "", 'ئ': "y", 'ؤ': "w", 'پ': "p", 'چ': "ch", 'ژ': "zh",
+	'گ': "g", 'ک': "k", 'ی': "y",
+	'ً': "", 'ٌ': "", 'ٍ': "", 'َ': "", 'ُ': "", 'ِ': "", 'ّ': "", 'ْ': "",
+}
+
+// skeletonDropped are the letters left out of consonant skeletons: the vowels, which Arabic script does not write
+// when they are short, and w and y, which stand for the long vowels in romanized Arabic
+const skeletonDropped = "aeiouwy"
+
+// kana maps hiragana to Hepburn romaji, katakana is shifted onto hiragana before the lookup
+var kana = map[rune]string{
+	'あ': "a", 'い': "i", 'う': "u", 'え': "e", 'お': "o",
+	'か': "ka", 'き': "ki", 'く': "ku", 'け': "ke", 'こ': "ko",
+	'が': "ga", 'ぎ': "gi", 'ぐ': "gu", 'げ': "ge", 'ご': "go",
+	'さ': "sa", 'し': "shi", 'す': "su", 'せ': "se", 'そ': "so",
+	'ざ': "za", 'じ': "ji", 'ず': "zu", 'ぜ': "ze", 'ぞ': "zo",
+	'た': "ta", 'ち': "chi", 'つ': "tsu", 'て': "te", 'と': "to",
+	'だ': "da", 'ぢ': "ji", 'づ': "zu", 'で': "de", 'ど': "do",
+	'な': "na", 'に': "ni", 'ぬ': "nu", 'ね': "ne", 'の': "no",
+	'は': "ha", 'ひ': "hi", 'ふ': "fu", 'へ': "he", 'ほ': "ho",
+	'ば': "ba", 'び': "bi", 'ぶ': "bu", 'べ': "be", 'ぼ': "bo",
+	'ぱ': "pa", 'ぴ': "pi", 'ぷ': "pu", 'ぺ': "pe", 'ぽ': "po",
+	'ま': "ma", 'み': "mi", 'む': "mu", 'め': "me", 'も': "mo",
+	'や': "ya", 'ゆ': "yu", 'よ': "yo",
+	'ら': "ra", 'り': "ri", 'る': "ru", 'れ': "re", 'ろ': "ro",
+	'わ': "wa", 'ゐ': "i", 'ゑ': "e", 'を': "o", 'ん': "n", 'ゔ': "vu",
+}
+
+// Hangul syllables are composed of an initial, a medial and an optional final jamo (Revised Romanization)
+var (
+	hangulInitials = []string{"g", "kk", "n", "d", "tt", "r", "m", "b", "pp", "s", "ss", "", "j", "jj", "ch", "k", "t", "p", "h"}
+	hangulMedials  = []string{"a", "ae", "ya", "yae", "eo", "e", "yeo", "ye", "o", "wa", "wae", "oe", "yo", "u", "wo", "we", "wi", "yu", "eu", "ui", "i"}
+	hangulFinals   = []string{"", "k", "k", "k", "n", "n", "n", "t", "l", "k", "m", "l", "l", "l", "p", "l", "m", "p", "p", "t", "t", "ng", "t", "t", "k", "t", "p", "t"}
+)
+
+var latin = make(map[rune]string)
+
+func init() {
+	for base, letters := range latinBase {
+		for _, r := range letters {
+			latin[r] = base
+		}
+	}
+}
+
+// transliterate rewrites a lower-cased name into Latin script
+func transliterate(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r < unicode.MaxASCII {
+			b.WriteRune(r)
+			continue
+		}
+		if r >= 0x30A1 && r <= 0x30F6 { // Katakana share the layout of hiragana
+			r -= 0x60
+		}
+		switch {
+		case r >= 0xAC00 && r <= 0xD7A3:
+			syllable := int(r - 0xAC00)
+			b.WriteString(hangulInitials[syllable/588])
+			b.WriteString(hangulMedials[(syllable%588)/28])
+			b.WriteString(hangulFinals[syllable%28])
+		case r >= 0x3041 && r <= 0x3096:
+			i += writeKana(&b, runes, i)
+		default:
+			if latinLetters, exists := latin[r]; exists {
+				b.WriteString(latinLetters)
+			} else if latinLetters, exists := cyrillic[r]; exists {
+				b.WriteString(latinLetters)
+			} else if latinLetters, exists := greek[r]; exists {
+				b.WriteString(latinLetters)
+			} else if latinLetters, exists := arabic[r]; exists {
+				b.WriteString(latinLetters)
+			} else if r == '・' { // The katakana middle dot separates the words of foreign names
+				b.WriteRune(' ')
+			} else if r != 'ー' { // The long vowel mark is not written in Hepburn
+				b.WriteRune(r)
+			}
+		}
+	}
+	return b.String()
+}
+
+// skeleton reduces a key to the consonants of its tokens, with doubled ones collapsed, so that "soldeu"
+// and its romanized Arabic spelling "swldw" share "sld"
+func skeleton(key string) string {
+	var tokens []string
+	for _, token := range strings.Fields(key) {
+		var b strings.Builder
+		var last rune
+		for _, r := range token {
+			if strings.ContainsRune(skeletonDropped, r) || r == last {
+				continue
+			}
+			b.WriteRune(r)
+			last = r
+		}
+		if b.Len() > 0 {
+			tokens = append(tokens, b.String())
+		}
+	}
+	return strings.Join(tokens, " ")
+}
+
+// isArabic reports whether a name is written in Arabic script
+func isArabic(name string) bool {
+	for _, r := range name {
+		if unicode.Is(unicode.Arabic, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeKana writes the kana at position i together with the small kana that modify it,
+// and returns how many extra runes it consumed
+func writeKana(b *strings.Builder, runes []rune, i int) int {
+	r := hiragana(runes[i])
+	if r == 'っ' { // The small tsu doubles the consonant that follows
+		if i+1 < len(runes) {
+			if next := kana[hiragana(runes[i+1])]; next != "" {
+				b.WriteByte(next[0])
+			}
+		}
+		return 0
+	}
+
+	romaji, exists := kana[r]
+	if !exists {
+		b.WriteRune(runes[i])
+		return 0
+	}
+	if i+1 >= len(runes) {
+		b.WriteString(romaji)
+		return 0
+	}
+
+	switch small := hiragana(runes[i+1]); small {
+	case 'ゃ', 'ゅ', 'ょ': // Contracted sounds, e.g. き+ゃ is "kya" and し+ゃ is "sha"
+		vowel := kana[small+1][1:]
+		stem := strings.TrimSuffix(romaji, "i")
+		if !strings.HasSuffix(stem, "sh") && !strings.HasSuffix(stem, "ch") && stem != "j" {
+			stem += "y"
+		}
+		b.WriteString(stem + vowel)
+		return 1
+	case 'ぁ', 'ぃ', 'ぅ', 'ぇ', 'ぉ': // Extended katakana, e.g. フ+ァ is "fa" and テ+ィ is "ti"
+		b.WriteString(romaji[:len(romaji)-1] + kana[small+1])
+		return 1
+	}
+	b.WriteString(romaji)
+	return 0
+}
+
+func hiragana(r rune) rune {
+	if r >= 0x30A1 && r <= 0x30F6 {
+		return r - 0x60
+	}
+	return r
+}
@@ -0,0 +1,117 @@
+package name
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SamyRai/cityFinder/lib/city"
+	"github.com/SamyRai/cityFinder/lib/dataLoader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransliterate(t *testing.T) {
+	cases := map[string]string{
+		"Sant Julià de Lòria":   "sant julia de loria",
+		"Port de l’Ovella":      "port de l’ovella",
+		"Казань":                "kazan",
+		"Эл Тартер":             "el tarter",
+		"Тоссалет і Віньяльс":   "tossalet i vinyals",
+		"Αθήνα":                 "athina",
+		"سولدو":                 "swldw",
+		"ال تارتر":              "al tartr",
+		"スルデウ":                  "surudeu",
+		"サン・ジュリア・デ・ロリア":         "san juria de roria",
+		"서울":                    "seoul",
+		"Düsseldorf-Gerresheim": "dusseldorf-gerresheim",
+	}
+	for name, expected := range cases {
+		assert.Equal(t, expected, transliterate(strings.ToLower(name)), name)
+	}
+}
+
+func TestFinder_SearchAcrossScripts(t *testing.T) {
+	cities, err := dataLoader.LoadGeoNamesCSV("../../../testdata/allCountries.txt")
+	require.NoError(t, err)
+	finder := NewNameFinder()
+	for _, spatialCity := range cities {
+		finder.AddCity(spatialCity)
+	}
+	finder.AddCity(city.SpatialCity{City: city.City{Name: "Казань", Country: "RU", Latitude: 55.7887, Longitude: 49.1221}})
+	finder.AddCity(city.SpatialCity{City: city.City{Name: "Seoul", Country: "AD", Latitude: 42.5, Longitude: 1.5}})
+
+	cases := map[string]string{
+		"Солдеу":              "Soldeu",              // Cyrillic query, Latin and Cyrillic names indexed
+		"Ордино":              "Ordino",              // Cyrillic alternate name
+		"Эль Тартер":          "El Tarter",           // Cyrillic query differing from the indexed one
+		"オルディノ":               "Ordino",              // Katakana, one letter away from the Latin key
+		"Sant Julia de Loria": "Sant Julià de Lòria", // Diacritics dropped
+		"Πας ντε λα Κάσα":     "Pas de la Casa",      // Greek, fuzzy
+		"サン・ジュリア・デ・ロリア":       "Sant Julià de Lòria", // Katakana words separated by middle dots
+		"奥尔迪诺":                "Ordino",              // Han alternate name, matched as written
+		"서울":                  "Seoul",               // Hangul query, Latin name indexed
+	}
+	for query, expected := range cases {
+		found := finder.CityByName(query, "AD")
+		if assert.NotNil(t, found, query) {
+			assert.Equal(t, expected, found.Name, query)
+		}
+	}
+
+	// Han is not transliterated, so Chinese and kanji names do not find places indexed by their Latin name only
+	assert.Nil(t, finder.CityByName("首尔", "AD"))
+	assert.Nil(t, finder.CityByName("ソウル市", "AD"))
+
+	// Latin query for a place indexed only in Cyrillic
+	found := finder.CityByName("Kazan", "RU")
+	if assert.NotNil(t, found) {
+		assert.Equal(t, "Казань", found.Name)
+	}
+}
+
+func TestSkeleton(t *testing.T) {
+	cases := map[string]string{
+		"soldeu":              "sld",
+		"swldw":               "sld",
+		"el tarter":           "l trtr",
+		"al tartr":            "l trtr",
+		"sant julia de loria": "snt jl d lr",
+		"sant jwlya dy lwrya": "snt jl d lr",
+		"ou":                  "",
+	}
+	for key, expected := range cases {
+		assert.Equal(t, expected, skeleton(key), key)
+	}
+}
+
+func TestFinder_SearchAcrossArabic(t *testing.T) {
+	cities, err := dataLoader.LoadGeoNamesCSV("../../../testdata/allCountries.txt")
+	require.NoError(t, err)
+	// Only the Latin names are indexed, so Arabic queries cannot fall back to Arabic alternate names
+	finder := NewNameFinder()
+	for _, spatialCity := range cities {
+		spatialCity.AltNames = nil
+		finder.AddCity(spatialCity)
+	}
+	finder.AddCity(city.SpatialCity{City: city.City{Name: "دبي", Country: "AE", Latitude: 25.0772, Longitude: 55.3093}})
+
+	cases := map[string]string{
+		"سولدو":               "Soldeu",
+		"ال تارتر":            "El Tarter",
+		"أوردينو":             "Ordino",
+		"سانت جوليا دي لوريا": "Sant Julià de Lòria",
+	}
+	for query, expected := range cases {
+		found := finder.CityByName(query, "AD")
+		if assert.NotNil(t, found, query) {
+			assert.Equal(t, expected, found.Name, query)
+		}
+	}
+
+	// Latin query for a place indexed only in Arabic
+	found := finder.CityByName("Dubai", "AE")
+	if assert.NotNil(t, found) {
+		assert.Equal(t, "دبي", found.Name)
+	}
+	assert.Empty(t, finder.Search(Query{Name: "سولدو", CountryCode: "AD", Match: MatchExact}))
+}
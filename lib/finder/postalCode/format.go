@@ -0,0 +1,200 @@
+package postalCode
+
+import (
+	"slices"
+	"strings"
+)
+
+// Format describes how the postal codes of a country are written.
+// Patterns use the GeoNames notation: '#' is a digit, '@' a letter and '*' either, anything else is literal.
+type Format struct {
+	Patterns []string // Accepted layouts, the first matching one gives the canonical spelling
+	Truncate int      // Characters to drop from a full code to get the part the basic GeoNames dump lists, e.g. the UK outward code
+}
+
+// truncatedCodes lists the countries of which the basic GeoNames postal code dump only has the leading part of
+// every code, with the number of characters left out, e.g. the inward code of UK postcodes. The full datasets,
+// such as GB_full, list complete codes.
+var truncatedCodes = map[string]int{
+	"AS": 4, "CA": 3, "FM": 4, "GB": 3, "GG": 3, "GU": 4, "IE": 4, "IM": 3, "JE": 3,
+	"MH": 4, "MP": 4, "NL": 2, "PR": 4, "PT": 3, "PW": 4, "US": 4, "VI": 4,
+}
+
+// Formats holds the postal code formats by country
+type Formats map[string]Format
+
+// NewFormats derives the postal code formats from the layouts of the GeoNames country table, which lists
+// the accepted layouts of a country separated by "|", e.g. "@# #@@|@## #@@" for GB. Countries whose codes the
+// basic postal code dump lists in part also accept the leading part of every layout, e.g. "@#" for "@# #@@".
+func NewFormats(layouts map[string]string) Formats {
+	formats := make(Formats, len(layouts))
+	for countryCode, layout := range layouts {
+		countryFormat := Format{Truncate: truncatedCodes[countryCode]}
+		for _, pattern := range strings.Split(layout, "|") {
+			if pattern = strings.TrimSpace(pattern); pattern != "" && !slices.Contains(countryFormat.Patterns, pattern) {
+				countryFormat.Patterns = append(countryFormat.Patterns, pattern)
+			}
+		}
+		if countryFormat.Truncate > 0 {
+			for _, pattern := range slices.Clone(countryFormat.Patterns) {
+				if leading, ok := truncatePattern(pattern, countryFormat.Truncate); ok && !slices.Contains(countryFormat.Patterns, leading) {
+					countryFormat.Patterns = append(countryFormat.Patterns, leading)
+				}
+			}
+		}
+		if len(countryFormat.Patterns) > 0 {
+			formats[countryCode] = countryFormat
+		}
+	}
+	return formats
+}
+
+// truncatePattern drops the given number of characters from the end of a pattern, together with the separators
+// before them. Literal codes such as "GIR0AA" are not truncated, as only the code itself fits them.
+func truncatePattern(pattern string, n int) (string, bool) {
+	if !strings.ContainsAny(pattern, "#@*") {
+		return "", false
+	}
+	end := len(pattern)
+	for ; end > 0 && n > 0; end-- {
+		if !isSeparator(pattern[end-1]) {
+			n--
+		}
+	}
+	leading := strings.TrimRightFunc(pattern[:end], func(r rune) bool { return r < 128 && isSeparator(byte(r)) })
+	return leading, leading != "" && n == 0
+}
+
+// separators are ignored when comparing postal codes
+var separators = strings.NewReplacer(" ", "", "-", "", ".", "", "\t", "")
+
+// Compact returns the upper-cased postal code without separators, the form the index is keyed by
+func Compact(postalCode string) string {
+	return separators.Replace(strings.ToUpper(strings.TrimSpace(postalCode)))
+}
+
+// Normalize returns the canonical spelling of a postal code in a country, e.g. "sw1a1aa" becomes "SW1A 1AA"
+// in GB and "1067" becomes "01067" in DE. It reports false when the code fits none of the country's formats,
+// or when the country has no known format.
+func (formats Formats) Normalize(postalCode, countryCode string) (string, bool) {
+	for _, candidate := range formats.candidates(Compact(postalCode), countryCode) {
+		if formatted, ok := formats.format(candidate, countryCode); ok {
+			return formatted, true
+		}
+	}
+	return "", false
+}
+
+// Patterns returns the accepted layouts of the postal codes of a country in GeoNames notation
+func (formats Formats) Patterns(countryCode string) []string {
+	return formats[countryCode].Patterns
+}
+
+// candidates returns the compact spellings a compact postal code may be stored under, most specific first:
+// the code itself, the code with its missing country prefix or leading zeros, and the part of it the basic
+// GeoNames dump lists, such as the outward code of a UK postcode
+func (formats Formats) candidates(compact, countryCode string) []string {
+	if compact == "" {
+		return nil
+	}
+	result := []string{compact}
+	add := func(candidate string) {
+		for _, existing := range result {
+			if existing == candidate {
+				return
+			}
+		}
+		result = append(result, candidate)
+	}
+
+	countryFormat, exists := formats[countryCode]
+	if !exists {
+		return result
+	}
+	for _, pattern := range countryFormat.Patterns {
+		compactPattern := Compact(pattern)
+		// Codes written without their country prefix, e.g. "500" for "AD500". Literal codes such as "GIR 0AA"
+		// have no prefix, they only match themselves.
+		if prefix := literalPrefix(compactPattern); prefix != "" && strings.ContainsAny(compactPattern, "#@*") && !strings.HasPrefix(compact, prefix) && len(prefix)+len(compact) == len(compactPattern) {
+			add(prefix + compact)
+		}
+		// Leading zeros lost by spreadsheets, e.g. "1067" for "01067"
+		if isDigits(compact) && len(compact) < len(pattern) && strings.Trim(pattern, "#") == "" {
+			add(strings.Repeat("0", len(pattern)-len(compact)) + compact)
+		}
+	}
+	// Both the full code and its truncated part must fit, so that an outward code is not truncated any further
+	if countryFormat.Truncate > 0 && len(compact) > countryFormat.Truncate {
+		truncated := compact[:len(compact)-countryFormat.Truncate]
+		_, fullFits := formats.format(compact, countryCode)
+		_, truncatedFits := formats.format(truncated, countryCode)
+		if fullFits && truncatedFits {
+			add(truncated)
+		}
+	}
+	return result
+}
+
+// format lays a compact postal code out along the first pattern of the country it fits
+func (formats Formats) format(compact, countryCode string) (string, bool) {
+	for _, pattern := range formats[countryCode].Patterns {
+		if formatted, ok := applyPattern(compact, pattern); ok {
+			return formatted, true
+		}
+	}
+	return "", false
+}
+
+// applyPattern lays a compact postal code out along a pattern, inserting the separators it contains
+func applyPattern(compact, pattern string) (string, bool) {
+	if len(compact) != len(Compact(pattern)) {
+		return "", false
+	}
+	var b strings.Builder
+	i := 0
+	for _, r := range pattern {
+		if r < 128 && isSeparator(byte(r)) {
+			b.WriteRune(r)
+			continue
+		}
+		c := compact[i]
+		i++
+		switch {
+		case r == '#' && isDigit(c), r == '@' && isLetter(c), r == '*' && (isDigit(c) || isLetter(c)), byte(r) == c:
+			b.WriteByte(c)
+		default:
+			return "", false
+		}
+	}
+	return b.String(), true
+}
+
+// literalPrefix returns the fixed letters a compact pattern starts with, such as "AD" in "AD###"
+func literalPrefix(compactPattern string) string {
+	end := 0
+	for end < len(compactPattern) && isLetter(compactPattern[end]) {
+		end++
+	}
+	return compactPattern[:end]
+}
+
+func isDigits(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if !isDigit(value[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isSeparator(c byte) bool {
+	return c == ' ' || c == '-' || c == '.'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,107 @@
+package postalCode
+
+import (
+	"testing"
+
+	"github.com/SamyRai/cityFinder/lib/dataLoader"
+	"github.com/stretchr/testify/assert"
+)
+
+// testFormats are the postal code layouts of the GeoNames country table for the countries under test
+var testFormats = NewFormats(map[string]string{
+	"AD": "AD###",
+	"CA": "@#@ #@#",
+	"DE": "#####",
+	"GB": "@# #@@|@## #@@|@@# #@@|@@## #@@|@#@ #@@|@@#@ #@@|GIR0AA",
+	"JP": "###-####",
+	"NL": "#### @@",
+	"PT": "####-###",
+	"US": "#####-####",
+	"AE": "",
+})
+
+func TestNewFormats(t *testing.T) {
+	// The basic dump lists the outward codes of UK postcodes, which are accepted as well
+	assert.Equal(t, []string{"@# #@@", "@## #@@", "@@# #@@", "@@## #@@", "@#@ #@@", "@@#@ #@@", "GIR0AA", "@#", "@##", "@@#", "@@##", "@#@", "@@#@"}, testFormats.Patterns("GB"))
+	assert.Equal(t, []string{"#####-####", "#####"}, testFormats.Patterns("US"))
+	assert.Equal(t, []string{"#####"}, testFormats.Patterns("DE"))
+	assert.Empty(t, testFormats.Patterns("AE"))
+
+	// Only the code itself fits a literal code, it is neither a prefix nor truncated
+	assert.Equal(t, []string{"W1A"}, testFormats.candidates("W1A", "GB"))
+	assert.Equal(t, []string{"GIR0AA"}, testFormats.candidates("GIR0AA", "GB"))
+}
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		postalCode, countryCode, expected string
+		wellFormed                        bool
+	}{
+		{"sw1a1aa", "GB", "SW1A 1AA", true},
+		{"SW1A 1AA", "GB", "SW1A 1AA", true},
+		{"m11ae", "GB", "M1 1AE", true},
+		{"sw1a", "GB", "SW1A", true},
+		{"1012ab", "NL", "1012 AB", true},
+		{"1012 AB", "NL", "1012 AB", true},
+		{"1067", "DE", "01067", true},
+		{"01067", "DE", "01067", true},
+		{"k1a0b1", "CA", "K1A 0B1", true},
+		{"500", "AD", "AD500", true},
+		{"1000001", "PT", "1000-001", true},
+		{"100-0001", "JP", "100-0001", true},
+		{"021341234", "US", "02134-1234", true},
+		{"ABCDE", "DE", "", false},
+		{"123456", "DE", "", false},
+		{"12345", "XX", "", false},
+		{"GIR 0AA", "GB", "GIR0AA", true},
+		{"gir0aa", "GB", "GIR0AA", true},
+		{"12345", "AE", "", false},
+	}
+	for _, tc := range cases {
+		normalized, wellFormed := testFormats.Normalize(tc.postalCode, tc.countryCode)
+		assert.Equal(t, tc.wellFormed, wellFormed, tc.postalCode)
+		assert.Equal(t, tc.expected, normalized, tc.postalCode)
+	}
+}
+
+func TestFinder_CityByPostalCodeNormalized(t *testing.T) {
+	finder := NewPostalCodeFinder()
+	finder.SetFormats(testFormats)
+	finder.AddPostalCode(dataLoader.PostalCodeEntry{CountryCode: "GB", PostalCode: "SW1A", PlaceName: "London"})
+	finder.AddPostalCode(dataLoader.PostalCodeEntry{CountryCode: "NL", PostalCode: "1012", PlaceName: "Amsterdam"})
+	finder.AddPostalCode(dataLoader.PostalCodeEntry{CountryCode: "DE", PostalCode: "01067", PlaceName: "Dresden"})
+	finder.AddPostalCode(dataLoader.PostalCodeEntry{CountryCode: "CA", PostalCode: "K1A", PlaceName: "Ottawa"})
+	finder.AddPostalCode(dataLoader.PostalCodeEntry{CountryCode: "CA", PostalCode: "K1A 0B1", PlaceName: "Ottawa Parliament Hill"})
+
+	cases := map[[2]string]string{
+		{"sw1a1aa", "GB"}:  "London",
+		{"SW1A 1AA", "GB"}: "London",
+		{"sw1a", "GB"}:     "London",
+		{"1012ab", "NL"}:   "Amsterdam",
+		{"1012 AB", "NL"}:  "Amsterdam",
+		{"1067", "DE"}:     "Dresden",
+		{"01067", "DE"}:    "Dresden",
+		{"k1a 0b1", "CA"}:  "Ottawa Parliament Hill",
+		{"K1A 0B2", "CA"}:  "Ottawa",
+	}
+	for query, expected := range cases {
+		places := finder.CityByPostalCode(query[0], query[1])
+		if assert.Len(t, places, 1, query[0]) {
+			assert.Equal(t, expected, places[0].Name, query[0])
+		}
+	}
+	assert.Empty(t, finder.CityByPostalCode("SW1B 1AA", "GB"))
+
+	validation := finder.Validate("1012ab", "NL")
+	assert.True(t, validation.WellFormed)
+	assert.True(t, validation.Exists)
+	assert.Equal(t, "1012 AB", validation.Normalized)
+
+	validation = finder.Validate("99999", "DE")
+	assert.True(t, validation.WellFormed)
+	assert.False(t, validation.Exists)
+
+	validation = finder.Validate("9999X", "DE")
+	assert.False(t, validation.WellFormed)
+	assert.Equal(t, []string{"#####"}, validation.Formats)
+}
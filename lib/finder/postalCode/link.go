@@ -0,0 +1,91 @@
+package postalCode
+
+import (
+	"github.com/SamyRai/cityFinder/lib/city"
+	"github.com/SamyRai/cityFinder/lib/dataLoader"
+	"github.com/SamyRai/cityFinder/lib/finder/name"
+	"github.com/cheggaaa/pb/v3"
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+)
+
+const (
+	maxNameLinkDistanceKm      = 30.0 // Distance up to which a place of the same name is linked
+	maxProximityLinkDistanceKm = 5.0  // Distance up to which the nearest place is linked when no name matches
+)
+
+// LinkPlaces associates every postal code entry with its best-matching populated place and copies the place's
+// geonameid, population and time zone into the entry. The nearest place whose name matches the entry's place name
+// within 30 km is preferred, otherwise the nearest populated place within 5 km is taken. Entries without a match
+// are left unlinked.
+func LinkPlaces(postalCodes map[string]map[string][]dataLoader.PostalCodeEntry, cities []city.SpatialCity) {
+	populated := make([]*city.City, 0, len(cities))
+	byName := make(map[string]map[string][]*city.City)
+	for i := range cities {
+		c := &cities[i].City
+		if c.FeatureClass != "P" {
+			continue
+		}
+		populated = append(populated, c)
+		if _, exists := byName[c.Country]; !exists {
+			byName[c.Country] = make(map[string][]*city.City)
+		}
+		for _, placeName := range append([]string{c.Name}, c.AltNames...) {
+			if key := name.Key(placeName, c.Country); key != "" {
+				byName[c.Country][key] = append(byName[c.Country][key], c)
+			}
+		}
+	}
+
+	points := make(s2.PointVector, len(populated))
+	for i, c := range populated {
+		points[i] = s2.PointFromLatLng(s2.LatLngFromDegrees(c.Latitude, c.Longitude))
+	}
+	index := s2.NewShapeIndex()
+	index.Add(&points)
+	options := s2.NewClosestEdgeQueryOptions().MaxResults(1).
+		DistanceLimit(s1.ChordAngleFromAngle(s1.Angle(maxProximityLinkDistanceKm / earthRadiusKm)))
+	query := s2.NewClosestEdgeQuery(index, options)
+
+	total := 0
+	for _, codes := range postalCodes {
+		for _, entries := range codes {
+			total += len(entries)
+		}
+	}
+	bar := pb.Full.Start(total)
+	for countryCode, codes := range postalCodes {
+		for _, entries := range codes {
+			for i := range entries {
+				entry := &entries[i]
+				place := nearestByName(byName[countryCode][name.Key(entry.PlaceName, countryCode)], entry)
+				if place == nil {
+					target := s2.NewMinDistanceToPointTarget(s2.PointFromLatLng(s2.LatLngFromDegrees(entry.Latitude, entry.Longitude)))
+					if results := query.FindEdges(target); len(results) > 0 && populated[results[0].EdgeID()].Country == countryCode {
+						place = populated[results[0].EdgeID()]
+					}
+				}
+				if place != nil {
+					entry.GeonameID = place.GeonameID
+					entry.Population = place.Population
+					entry.Timezone = place.Timezone
+				}
+				bar.Increment()
+			}
+		}
+	}
+	bar.Finish()
+}
+
+// nearestByName returns the candidate closest to an entry within the distance allowed for name matches
+func nearestByName(candidates []*city.City, entry *dataLoader.PostalCodeEntry) *city.City {
+	var nearest *city.City
+	nearestDistance := maxNameLinkDistanceKm
+	for _, candidate := range candidates {
+		distance := city.HaversineDistance(entry.Latitude, entry.Longitude, candidate.Latitude, candidate.Longitude)
+		if distance <= nearestDistance {
+			nearest, nearestDistance = candidate, distance
+		}
+	}
+	return nearest
+}
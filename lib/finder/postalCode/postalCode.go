@@ -2,76 +2,331 @@ package postalCode
 
 import (
 	"encoding/gob"
+	"fmt"
 	"github.com/SamyRai/cityFinder/lib/city"
 	"github.com/SamyRai/cityFinder/lib/dataLoader"
+	"github.com/SamyRai/cityFinder/lib/finder/name"
 	"github.com/cheggaaa/pb/v3"
 	"os"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 )
 
+// IndexVersion is the format version of the serialized postal code index. Bump it whenever the layout of the index
+// changes, so that index files written before are rebuilt instead of misread.
+const IndexVersion = 2
+
+// IndexSettings are the datasets a postal code index is built from. They are stored in the header of the index file,
+// and files built from other datasets are rejected, so that changing the list rebuilds the index.
+type IndexSettings struct {
+	PostalCodesFile string    // Main postal code file
+	Datasets        []Dataset // Additional postal code files, in the order they are merged
+}
+
+// Dataset is an additional postal code file merged into the index
+type Dataset struct {
+	File    string
+	Replace bool // The dataset replaces every earlier entry of the countries it covers
+}
+
+// matches reports whether an index built with the settings can serve a configuration asking for the other ones
+func (s IndexSettings) matches(other IndexSettings) bool {
+	return s.PostalCodesFile == other.PostalCodesFile && slices.Equal(s.Datasets, other.Datasets)
+}
+
 // Finder is a struct that contains the data for postal code lookups
 type Finder struct {
-	PostalCode map[string]map[string]dataLoader.PostalCodeEntry // Map of country code to postal code to entry
-	mutex      sync.RWMutex                                     // Mutex for thread-safe operations
+	PostalCode map[string]map[string][]dataLoader.PostalCodeEntry // Map of country code to compact postal code to the entries of the places it covers
+	Keys       map[string][]string                                // Map of country code to its compact postal codes in ascending order, for prefix and range searches
+	Names      map[string]map[string][]string                     // Map of country code to normalized place name key to the compact postal codes covering it
+	PlaceCodes map[int][]string                                   // Map of geonameid to the postal codes of the entries linked to the place
+	unsorted   map[string]bool                                    // Countries with postal codes added since their keys were last sorted
+	settings   IndexSettings                                      // Datasets the index was built from, written in the header of the index file
+	formats    Formats                                            // Postal code formats by country, taken from the country table
+	mutex      sync.RWMutex                                       // Mutex for thread-safe operations
+}
+
+// Place is a place covered by a postal code, together with its full admin hierarchy and the accuracy of its coordinates.
+// The admin codes are those of the embedded city.
+type Place struct {
+	city.City
+	PostalCode string
+	AdminName1 string
+	AdminName2 string
+	AdminName3 string
+	Accuracy   int // 1 = estimated, 4 = geonameid, 6 = centroid of addresses or shape, 0 = unknown
+}
+
+// FilterByAccuracy returns the places whose coordinates are at least as accurate as minAccuracy, in the same order
+func FilterByAccuracy(places []Place, minAccuracy int) []Place {
+	if minAccuracy <= 0 {
+		return places
+	}
+	filtered := make([]Place, 0, len(places))
+	for _, place := range places {
+		if place.Accuracy >= minAccuracy {
+			filtered = append(filtered, place)
+		}
+	}
+	return filtered
 }
 
 // NewPostalCodeFinder creates a new Finder instance
 func NewPostalCodeFinder() *Finder {
 	return &Finder{
-		PostalCode: make(map[string]map[string]dataLoader.PostalCodeEntry),
+		PostalCode: make(map[string]map[string][]dataLoader.PostalCodeEntry),
+		Keys:       make(map[string][]string),
+		Names:      make(map[string]map[string][]string),
+		PlaceCodes: make(map[int][]string),
+		unsorted:   make(map[string]bool),
 	}
 }
 
-// AddPostalCode adds a postal code entry to the Finder
+// SetFormats sets the postal code formats of the countries, which codes are normalized and validated against.
+// Without them codes are only matched as written, regardless of case and separators.
+func (pcf *Finder) SetFormats(formats Formats) {
+	pcf.mutex.Lock()
+	defer pcf.mutex.Unlock()
+
+	pcf.formats = formats
+}
+
+// AddPostalCode adds a postal code entry to the Finder, next to the other places sharing its code
 func (pcf *Finder) AddPostalCode(entry dataLoader.PostalCodeEntry) {
 	pcf.mutex.Lock()
 	defer pcf.mutex.Unlock()
 
 	if _, exists := pcf.PostalCode[entry.CountryCode]; !exists {
-		pcf.PostalCode[entry.CountryCode] = make(map[string]dataLoader.PostalCodeEntry)
+		pcf.PostalCode[entry.CountryCode] = make(map[string][]dataLoader.PostalCodeEntry)
+	}
+	key := Compact(entry.PostalCode)
+	if _, exists := pcf.PostalCode[entry.CountryCode][key]; !exists {
+		// Keys are sorted once before the next search rather than on every insertion
+		pcf.Keys[entry.CountryCode] = append(pcf.Keys[entry.CountryCode], key)
+		pcf.unsorted[entry.CountryCode] = true
+	}
+	pcf.addName(entry, key)
+	pcf.addPlaceCode(entry)
+	pcf.PostalCode[entry.CountryCode][key] = append(pcf.PostalCode[entry.CountryCode][key], entry)
+}
+
+// addName maps the place name of an entry to its postal code, the caller must hold the write lock
+func (pcf *Finder) addName(entry dataLoader.PostalCodeEntry, key string) {
+	nameKey := name.Key(entry.PlaceName, entry.CountryCode)
+	if nameKey == "" {
+		return
+	}
+	if _, exists := pcf.Names[entry.CountryCode]; !exists {
+		pcf.Names[entry.CountryCode] = make(map[string][]string)
+	}
+	codes := pcf.Names[entry.CountryCode][nameKey]
+	// Entries of a postal code are usually added together, other repeats are skipped when searching
+	if len(codes) == 0 || codes[len(codes)-1] != key {
+		pcf.Names[entry.CountryCode][nameKey] = append(codes, key)
 	}
-	pcf.PostalCode[entry.CountryCode][entry.PostalCode] = entry
 }
 
-// BuildIndex creates a postal code index from postal code data
-func BuildIndex(postalCodes map[string]map[string]dataLoader.PostalCodeEntry) *Finder {
+// BuildIndex creates a postal code index from postal code data, merged from the datasets of the settings
+func BuildIndex(postalCodes map[string]map[string][]dataLoader.PostalCodeEntry, settings IndexSettings) *Finder {
 	finder := NewPostalCodeFinder()
+	finder.settings = settings
 	total := 0
 	for _, countryCode := range postalCodes {
-		for range countryCode {
-			total++
+		for _, entries := range countryCode {
+			total += len(entries)
 		}
 	}
 	bar := pb.Full.Start(total)
 	for _, countryCode := range postalCodes {
-		for _, entry := range countryCode {
-			finder.AddPostalCode(entry)
-			bar.Increment()
+		for _, entries := range countryCode {
+			for _, entry := range entries {
+				finder.AddPostalCode(entry)
+				bar.Increment()
+			}
 		}
 	}
 	bar.Finish()
+	finder.ensureSorted()
 
 	return finder
 }
 
-// CityByPostalCode finds the nearest city by postal code and country code
-func (pcf *Finder) CityByPostalCode(postalCode, countryCode string) *city.City {
+// Validation reports whether a postal code is well-formed in a country and whether it is known
+type Validation struct {
+	PostalCode  string
+	CountryCode string
+	Normalized  string   // Canonical spelling of the code, empty if it is not well-formed
+	WellFormed  bool     // The code fits one of the country's formats
+	Exists      bool     // The code, or the part of it the dataset lists, is in the index
+	Formats     []string // Accepted layouts in GeoNames notation, '#' for a digit and '@' for a letter
+}
+
+// CityByPostalCode returns all places covered by a postal code in a country, in dataset order.
+// The code is matched regardless of case and separators, and falls back to the forms the country's
+// rules allow, e.g. a missing leading zero or the outward part of a UK postcode.
+func (pcf *Finder) CityByPostalCode(postalCode, countryCode string) []Place {
 	pcf.mutex.RLock()
 	defer pcf.mutex.RUnlock()
 
-	if countryEntries, exists := pcf.PostalCode[countryCode]; exists {
-		if entry, exists := countryEntries[postalCode]; exists {
-			return &city.City{
-				Latitude:  entry.Latitude,
-				Longitude: entry.Longitude,
-				Name:      entry.PlaceName,
-				Country:   countryCode,
-			}
+	entries := pcf.lookup(postalCode, countryCode)
+	places := make([]Place, 0, len(entries))
+	for _, entry := range entries {
+		places = append(places, NewPlace(entry))
+	}
+	return places
+}
+
+// Validate checks a postal code against the formats of a country and the index
+func (pcf *Finder) Validate(postalCode, countryCode string) Validation {
+	pcf.mutex.RLock()
+	defer pcf.mutex.RUnlock()
+
+	normalized, wellFormed := pcf.formats.Normalize(postalCode, countryCode)
+	return Validation{
+		PostalCode:  postalCode,
+		CountryCode: countryCode,
+		Normalized:  normalized,
+		WellFormed:  wellFormed,
+		Exists:      len(pcf.lookup(postalCode, countryCode)) > 0,
+		Formats:     pcf.formats.Patterns(countryCode),
+	}
+}
+
+// Centroid returns the mean position of the places covered by a postal code, which is looked up like in CityByPostalCode
+func (pcf *Finder) Centroid(postalCode, countryCode string) (lat, lon float64, ok bool) {
+	pcf.mutex.RLock()
+	defer pcf.mutex.RUnlock()
+
+	entries := pcf.lookup(postalCode, countryCode)
+	for _, entry := range entries {
+		lat += entry.Latitude / float64(len(entries))
+		lon += entry.Longitude / float64(len(entries))
+	}
+	return lat, lon, len(entries) > 0
+}
+
+// lookup returns the entries of the first spelling of a postal code the index knows
+func (pcf *Finder) lookup(postalCode, countryCode string) []dataLoader.PostalCodeEntry {
+	for _, candidate := range pcf.formats.candidates(Compact(postalCode), countryCode) {
+		if entries, exists := pcf.PostalCode[countryCode][candidate]; exists {
+			return entries
 		}
 	}
 	return nil
 }
 
+// NewPlace converts a postal code entry into a place
+func NewPlace(entry dataLoader.PostalCodeEntry) Place {
+	return Place{
+		City: city.City{
+			GeonameID:  entry.GeonameID,
+			Latitude:   entry.Latitude,
+			Longitude:  entry.Longitude,
+			Name:       entry.PlaceName,
+			Country:    entry.CountryCode,
+			Admin1Code: entry.AdminCode1,
+			Admin2Code: entry.AdminCode2,
+			Admin3Code: entry.AdminCode3,
+			Population: entry.Population,
+			Timezone:   entry.Timezone,
+		},
+		PostalCode: entry.PostalCode,
+		AdminName1: entry.AdminName1,
+		AdminName2: entry.AdminName2,
+		AdminName3: entry.AdminName3,
+		Accuracy:   entry.Accuracy,
+	}
+}
+
+// addPlaceCode records the postal code of an entry under its linked place, the caller must hold the write lock
+func (pcf *Finder) addPlaceCode(entry dataLoader.PostalCodeEntry) {
+	if entry.GeonameID == 0 {
+		return
+	}
+	codes := pcf.PlaceCodes[entry.GeonameID]
+	if len(codes) == 0 || codes[len(codes)-1] != entry.PostalCode {
+		pcf.PlaceCodes[entry.GeonameID] = append(codes, entry.PostalCode)
+	}
+}
+
+// PostalCodes returns the postal codes linked to a GeoNames place, in ascending order
+func (pcf *Finder) PostalCodes(geonameID int) []string {
+	pcf.mutex.RLock()
+	defer pcf.mutex.RUnlock()
+
+	codes := append([]string(nil), pcf.PlaceCodes[geonameID]...)
+	sort.Strings(codes)
+	unique := codes[:0]
+	for i, code := range codes {
+		if i == 0 || codes[i-1] != code {
+			unique = append(unique, code)
+		}
+	}
+	return unique
+}
+
+// ByPlaceName returns the places with a name in a country, each with its postal code, ordered by postal code.
+// Names are normalized like in the name index, so "Sankt Gallen" finds "St. Gallen". An empty country searches
+// all of them in alphabetical order; a region restricts the places to a first-order division by code or name.
+func (pcf *Finder) ByPlaceName(placeName, countryCode, region string) []Place {
+	pcf.ensureSorted()
+	pcf.mutex.RLock()
+	defer pcf.mutex.RUnlock()
+
+	countries := []string{countryCode}
+	if countryCode == "" {
+		countries = make([]string, 0, len(pcf.Names))
+		for code := range pcf.Names {
+			countries = append(countries, code)
+		}
+		sort.Strings(countries)
+	}
+
+	places := make([]Place, 0)
+	for _, country := range countries {
+		nameKey := name.Key(placeName, country)
+		if nameKey == "" {
+			continue
+		}
+		codes := append([]string(nil), pcf.Names[country][nameKey]...)
+		sort.Strings(codes)
+		for i, code := range codes {
+			if i > 0 && codes[i-1] == code {
+				continue
+			}
+			for _, entry := range pcf.PostalCode[country][code] {
+				if name.Key(entry.PlaceName, country) == nameKey && inRegion(entry, region) {
+					places = append(places, NewPlace(entry))
+				}
+			}
+		}
+	}
+	return places
+}
+
+// inRegion reports whether an entry lies in a first-order division given by code or name, an empty region matches all
+func inRegion(entry dataLoader.PostalCodeEntry, region string) bool {
+	region = strings.TrimSpace(region)
+	return region == "" || strings.EqualFold(entry.AdminCode1, region) || strings.EqualFold(entry.AdminName1, region)
+}
+
+// Countries returns the codes of the countries in which the postal code exists, in alphabetical order
+func (pcf *Finder) Countries(postalCode string) []string {
+	pcf.mutex.RLock()
+	defer pcf.mutex.RUnlock()
+
+	var countries []string
+	for countryCode := range pcf.PostalCode {
+		if len(pcf.lookup(postalCode, countryCode)) > 0 {
+			countries = append(countries, countryCode)
+		}
+	}
+	sort.Strings(countries)
+	return countries
+}
+
 // SerializeIndex saves the postal code index to a file
 func (pcf *Finder) SerializeIndex(filepath string) error {
 	pcf.mutex.Lock()
@@ -82,8 +337,15 @@ func (pcf *Finder) SerializeIndex(filepath string) error {
 		return err
 	}
 
+	pcf.sortKeys()
 	encoder := gob.NewEncoder(file)
-	encodeErr := encoder.Encode(pcf)
+	encodeErr := encoder.Encode(IndexVersion)
+	if encodeErr == nil {
+		encodeErr = encoder.Encode(pcf.settings)
+	}
+	if encodeErr == nil {
+		encodeErr = encoder.Encode(pcf)
+	}
 	closeErr := file.Close()
 
 	if encodeErr != nil {
@@ -92,16 +354,29 @@ func (pcf *Finder) SerializeIndex(filepath string) error {
 	return closeErr
 }
 
-// DeserializeIndex loads the postal code index from a file
-func DeserializeIndex(filepath string) (*Finder, error) {
+// DeserializeIndex loads the postal code index from a file. Files of another format version, or built from other
+// datasets, are rejected.
+func DeserializeIndex(filepath string, settings IndexSettings) (*Finder, error) {
 	file, err := os.Open(filepath)
 	if err != nil {
 		return nil, err
 	}
 
 	decoder := gob.NewDecoder(file)
+	var version int
 	var finder Finder
-	decodeErr := decoder.Decode(&finder)
+	decodeErr := decoder.Decode(&version)
+	if decodeErr != nil {
+		decodeErr = fmt.Errorf("failed to read index version: %v", decodeErr)
+	} else if version != IndexVersion {
+		decodeErr = fmt.Errorf("index format version %d, expected %d", version, IndexVersion)
+	} else if decodeErr = decoder.Decode(&finder.settings); decodeErr != nil {
+		decodeErr = fmt.Errorf("failed to read index settings: %v", decodeErr)
+	} else if !finder.settings.matches(settings) {
+		decodeErr = fmt.Errorf("index built with settings %+v, expected %+v", finder.settings, settings)
+	} else {
+		decodeErr = decoder.Decode(&finder)
+	}
 	closeErr := file.Close()
 
 	if decodeErr != nil {
@@ -111,5 +386,31 @@ func DeserializeIndex(filepath string) (*Finder, error) {
 		return nil, closeErr
 	}
 
+	// Indexes of earlier format versions, such as those written before the entries were linked to places,
+	// are rejected above and rebuilt from the datasets, so the decoded index is complete
+	finder.unsorted = make(map[string]bool)
+
 	return &finder, nil
 }
+
+// ensureSorted sorts the keys of the countries that received postal codes since the last search
+func (pcf *Finder) ensureSorted() {
+	pcf.mutex.RLock()
+	dirty := len(pcf.unsorted) > 0
+	pcf.mutex.RUnlock()
+	if !dirty {
+		return
+	}
+
+	pcf.mutex.Lock()
+	defer pcf.mutex.Unlock()
+	pcf.sortKeys()
+}
+
+// sortKeys sorts the pending keys, the caller must hold the write lock
+func (pcf *Finder) sortKeys() {
+	for countryCode := range pcf.unsorted {
+		sort.Strings(pcf.Keys[countryCode])
+	}
+	clear(pcf.unsorted)
+}
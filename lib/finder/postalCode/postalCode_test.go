@@ -0,0 +1,246 @@
+package postalCode
+
+import (
+	"encoding/gob"
+	"os"
+	"testing"
+
+	"github.com/SamyRai/cityFinder/lib/city"
+	"github.com/SamyRai/cityFinder/lib/dataLoader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFinder_SharedPostalCode(t *testing.T) {
+	finder := NewPostalCodeFinder()
+	finder.AddPostalCode(dataLoader.PostalCodeEntry{CountryCode: "DE", PostalCode: "54298", PlaceName: "Aach", AdminName1: "Rheinland-Pfalz", Accuracy: 4})
+	finder.AddPostalCode(dataLoader.PostalCodeEntry{CountryCode: "DE", PostalCode: "54298", PlaceName: "Igel", AdminName1: "Rheinland-Pfalz", Accuracy: 4})
+	finder.AddPostalCode(dataLoader.PostalCodeEntry{CountryCode: "DE", PostalCode: "54298", PlaceName: "Welschbillig", AdminName1: "Rheinland-Pfalz", Accuracy: 4})
+
+	places := finder.CityByPostalCode("54298", "DE")
+	require.Len(t, places, 3)
+	assert.Equal(t, "Aach", places[0].Name)
+	assert.Equal(t, "Welschbillig", places[2].Name)
+	assert.Equal(t, "Rheinland-Pfalz", places[1].AdminName1)
+	assert.Equal(t, 4, places[1].Accuracy)
+	assert.Len(t, FilterByAccuracy(places, 4), 3)
+	assert.Empty(t, FilterByAccuracy(places, 5))
+	assert.Empty(t, finder.CityByPostalCode("54298", "AT"))
+
+	tmpfile, err := os.CreateTemp("", "test_postal_code_finder_*.gob")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.Remove(tmpfile.Name())
+	}()
+	require.NoError(t, finder.SerializeIndex(tmpfile.Name()))
+	deserializedFinder, err := DeserializeIndex(tmpfile.Name(), IndexSettings{})
+	require.NoError(t, err)
+	assert.Equal(t, places, deserializedFinder.CityByPostalCode("54298", "DE"))
+
+	// Indexes written before the format was versioned start with the finder itself
+	file, err := os.Create(tmpfile.Name())
+	require.NoError(t, err)
+	require.NoError(t, gob.NewEncoder(file).Encode(finder))
+	require.NoError(t, file.Close())
+	_, err = DeserializeIndex(tmpfile.Name(), IndexSettings{})
+	assert.Error(t, err)
+}
+
+func TestDeserializeIndex_OtherDatasets(t *testing.T) {
+	settings := IndexSettings{PostalCodesFile: "allCountries.txt", Datasets: []Dataset{{File: "GB_full.txt"}, {File: "NL_full.txt", Replace: true}}}
+	finder := BuildIndex(nil, settings)
+
+	tmpfile, err := os.CreateTemp("", "test_postal_code_finder_*.gob")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.Remove(tmpfile.Name())
+	}()
+	require.NoError(t, finder.SerializeIndex(tmpfile.Name()))
+	_, err = DeserializeIndex(tmpfile.Name(), settings)
+	require.NoError(t, err)
+
+	// Adding, dropping or reordering a dataset, or changing how it is merged, rebuilds the index
+	for _, other := range []IndexSettings{
+		{PostalCodesFile: "allCountries.txt", Datasets: []Dataset{{File: "GB_full.txt"}, {File: "NL_full.txt", Replace: true}, {File: "CA_full.txt"}}},
+		{PostalCodesFile: "allCountries.txt", Datasets: []Dataset{{File: "GB_full.txt"}}},
+		{PostalCodesFile: "allCountries.txt", Datasets: []Dataset{{File: "NL_full.txt", Replace: true}, {File: "GB_full.txt"}}},
+		{PostalCodesFile: "allCountries.txt", Datasets: []Dataset{{File: "GB_full.txt"}, {File: "NL_full.txt"}}},
+		{PostalCodesFile: "DE.txt", Datasets: settings.Datasets},
+	} {
+		_, err = DeserializeIndex(tmpfile.Name(), other)
+		assert.ErrorContains(t, err, "settings", other)
+	}
+}
+
+func searchCodes(result SearchResult) []string {
+	codes := make([]string, len(result.Codes))
+	for i, code := range result.Codes {
+		codes[i] = code.PostalCode
+	}
+	return codes
+}
+
+func TestFinder_SearchPrefixAndRange(t *testing.T) {
+	finder := NewPostalCodeFinder()
+	finder.SetFormats(testFormats)
+	for _, code := range []string{"80999", "10115", "80331", "10117", "81000", "80000", "11011"} {
+		finder.AddPostalCode(dataLoader.PostalCodeEntry{CountryCode: "DE", PostalCode: code, PlaceName: "Place " + code, Latitude: 1, Longitude: 2})
+	}
+	finder.AddPostalCode(dataLoader.PostalCodeEntry{CountryCode: "DE", PostalCode: "10115", PlaceName: "Mitte", Latitude: 3, Longitude: 4})
+	for _, code := range []string{"SW1A", "SW1E", "SW10", "SW1A 1AA", "SW1A 2AA"} {
+		finder.AddPostalCode(dataLoader.PostalCodeEntry{CountryCode: "GB", PostalCode: code, PlaceName: "London"})
+	}
+
+	result := finder.SearchPrefix("10*", "DE", 0)
+	assert.Equal(t, []string{"10115", "10117"}, searchCodes(result))
+	assert.Equal(t, []string{"Place 10115", "Mitte"}, result.Codes[0].PlaceNames)
+	assert.Equal(t, 2.0, result.Codes[0].Latitude)
+	assert.Equal(t, 3.0, result.Codes[0].Longitude)
+
+	result = finder.SearchPrefix("1*", "DE", 2)
+	assert.Equal(t, []string{"10115", "10117"}, searchCodes(result))
+	assert.True(t, result.Truncated)
+
+	assert.Equal(t, []string{"SW1A", "SW1A 1AA", "SW1A 2AA"}, searchCodes(finder.SearchPrefix("sw1a", "GB", 0)))
+	assert.Equal(t, []string{"SW10", "SW1A", "SW1A 1AA", "SW1A 2AA", "SW1E"}, searchCodes(finder.SearchPrefix("SW1*", "GB", 0)))
+	assert.Empty(t, finder.SearchPrefix("SW1", "GB", 0).Codes)
+
+	result, err := finder.SearchRange("80000", "80999", "DE", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"80000", "80331", "80999"}, searchCodes(result))
+
+	_, err = finder.SearchRange("80999", "80000", "DE", 0)
+	assert.Error(t, err)
+	_, err = finder.SearchRange("800000", "80999", "DE", 0)
+	assert.Error(t, err)
+}
+
+func TestSpatialIndex_Nearest(t *testing.T) {
+	finder := NewPostalCodeFinder()
+	finder.AddPostalCode(dataLoader.PostalCodeEntry{CountryCode: "AD", PostalCode: "AD500", PlaceName: "Andorra la Vella", Latitude: 42.5, Longitude: 1.5, Accuracy: 6})
+	finder.AddPostalCode(dataLoader.PostalCodeEntry{CountryCode: "AD", PostalCode: "AD700", PlaceName: "Escaldes-Engordany", Latitude: 42.5, Longitude: 1.5667, Accuracy: 6})
+	finder.AddPostalCode(dataLoader.PostalCodeEntry{CountryCode: "AD", PostalCode: "AD100", PlaceName: "Canillo", Latitude: 42.5667, Longitude: 1.6, Accuracy: 6})
+	index := BuildSpatialIndex(finder)
+
+	places, err := index.Nearest(42.5, 1.51, 2, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, places, 2)
+	assert.Equal(t, "AD500", places[0].PostalCode)
+	assert.Equal(t, "AD700", places[1].PostalCode)
+	assert.InDelta(t, 0.82, places[0].DistanceKm, 0.01)
+
+	places, err = index.Nearest(42.5, 1.5, 0, 6, 0)
+	require.NoError(t, err)
+	assert.Len(t, places, 2)
+
+	finder.AddPostalCode(dataLoader.PostalCodeEntry{CountryCode: "AD", PostalCode: "AD600", PlaceName: "Sant Julià de Lòria", Latitude: 42.5, Longitude: 1.51, Accuracy: 1})
+	index = BuildSpatialIndex(finder)
+	places, err = index.Nearest(42.5, 1.51, 1, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "AD600", places[0].PostalCode)
+	places, err = index.Nearest(42.5, 1.51, 1, 0, 4)
+	require.NoError(t, err)
+	require.Len(t, places, 1)
+	assert.Equal(t, "AD500", places[0].PostalCode)
+
+	finder.AddPostalCode(dataLoader.PostalCodeEntry{CountryCode: "AD", PostalCode: "AD 700", PlaceName: "Engordany", Latitude: 42.51, Longitude: 1.5667, Accuracy: 6})
+	index = BuildSpatialIndex(finder)
+	places, err = index.Around(42.5, 1.5, 6, 0)
+	require.NoError(t, err)
+	require.Len(t, places, 3)
+	assert.Equal(t, "AD700", places[2].PostalCode)
+	assert.Equal(t, "Escaldes-Engordany", places[2].Name)
+	lat, lon, ok := finder.Centroid("ad700", "AD")
+	assert.True(t, ok)
+	assert.InDelta(t, 42.505, lat, 1e-9)
+	assert.InDelta(t, 1.5667, lon, 1e-9)
+
+	_, err = index.Nearest(42.5, 1.5, 0, 0, 0)
+	assert.Error(t, err)
+
+	tmpfile, err := os.CreateTemp("", "test_postal_code_s2index_*.gob")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.Remove(tmpfile.Name())
+	}()
+	require.NoError(t, index.SerializeIndex(tmpfile.Name()))
+	deserializedIndex, err := DeserializeSpatialIndex(tmpfile.Name())
+	require.NoError(t, err)
+	deserializedPlaces, err := deserializedIndex.Nearest(42.5, 1.51, 2, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "AD600", deserializedPlaces[0].PostalCode)
+
+	// Index files written before the format was versioned start with the entries themselves
+	file, err := os.Create(tmpfile.Name())
+	require.NoError(t, err)
+	require.NoError(t, gob.NewEncoder(file).Encode(SerializableSpatialIndex{Entries: index.Entries}))
+	require.NoError(t, file.Close())
+	_, err = DeserializeSpatialIndex(tmpfile.Name())
+	assert.Error(t, err)
+}
+
+func TestFinder_ByPlaceName(t *testing.T) {
+	finder := NewPostalCodeFinder()
+	finder.AddPostalCode(dataLoader.PostalCodeEntry{CountryCode: "CH", PostalCode: "9008", PlaceName: "St. Gallen", AdminCode1: "SG", AdminName1: "Kanton St. Gallen"})
+	finder.AddPostalCode(dataLoader.PostalCodeEntry{CountryCode: "CH", PostalCode: "9000", PlaceName: "St. Gallen", AdminCode1: "SG", AdminName1: "Kanton St. Gallen"})
+	finder.AddPostalCode(dataLoader.PostalCodeEntry{CountryCode: "CH", PostalCode: "9000", PlaceName: "Other", AdminCode1: "SG"})
+	finder.AddPostalCode(dataLoader.PostalCodeEntry{CountryCode: "DE", PostalCode: "10115", PlaceName: "Berlin", AdminCode1: "BE"})
+	finder.AddPostalCode(dataLoader.PostalCodeEntry{CountryCode: "US", PostalCode: "08009", PlaceName: "Berlin", AdminCode1: "NJ", AdminName1: "New Jersey"})
+
+	places := finder.ByPlaceName("Sankt Gallen", "CH", "")
+	require.Len(t, places, 2)
+	assert.Equal(t, "9000", places[0].PostalCode)
+	assert.Equal(t, "9008", places[1].PostalCode)
+
+	places = finder.ByPlaceName("berlin", "", "")
+	require.Len(t, places, 2)
+	assert.Equal(t, "DE", places[0].Country)
+	assert.Equal(t, "US", places[1].Country)
+
+	places = finder.ByPlaceName("Berlin", "", "new jersey")
+	require.Len(t, places, 1)
+	assert.Equal(t, "08009", places[0].PostalCode)
+	assert.Empty(t, finder.ByPlaceName("Berlin", "AT", ""))
+}
+
+func TestLinkPlaces(t *testing.T) {
+	cities := []city.SpatialCity{
+		{City: city.City{GeonameID: 1, Name: "Ordino", Country: "AD", FeatureClass: "A", Latitude: 42.5976, Longitude: 1.5257}},
+		{City: city.City{GeonameID: 2, Name: "Ordino", Country: "AD", FeatureClass: "P", Latitude: 42.5562, Longitude: 1.5332, Population: 3066, Timezone: "Europe/Andorra"}},
+		{City: city.City{GeonameID: 3, Name: "Encamp", Country: "AD", FeatureClass: "P", Latitude: 42.5346, Longitude: 1.5801}},
+		{City: city.City{GeonameID: 4, Name: "Pas de la Casa", Country: "AD", FeatureClass: "P", Latitude: 42.5427, Longitude: 1.7336}},
+	}
+	postalCodes := map[string]map[string][]dataLoader.PostalCodeEntry{
+		"AD": {
+			"AD300": {{CountryCode: "AD", PostalCode: "AD300", PlaceName: "Ordino", Latitude: 42.6, Longitude: 1.55}},
+			"AD200": {{CountryCode: "AD", PostalCode: "AD200", PlaceName: "Les Bons", Latitude: 42.5333, Longitude: 1.5833}},
+			"AD900": {{CountryCode: "AD", PostalCode: "AD900", PlaceName: "Nowhere", Latitude: 42.7, Longitude: 1.9}},
+		},
+	}
+	LinkPlaces(postalCodes, cities)
+
+	assert.Equal(t, 2, postalCodes["AD"]["AD300"][0].GeonameID)
+	assert.Equal(t, int64(3066), postalCodes["AD"]["AD300"][0].Population)
+	assert.Equal(t, "Europe/Andorra", postalCodes["AD"]["AD300"][0].Timezone)
+	assert.Equal(t, 3, postalCodes["AD"]["AD200"][0].GeonameID)
+	assert.Zero(t, postalCodes["AD"]["AD900"][0].GeonameID)
+
+	finder := BuildIndex(postalCodes, IndexSettings{})
+	assert.Equal(t, []string{"AD300"}, finder.PostalCodes(2))
+	assert.Empty(t, finder.PostalCodes(1))
+	places := finder.CityByPostalCode("AD300", "AD")
+	require.Len(t, places, 1)
+	assert.Equal(t, 2, places[0].GeonameID)
+
+	// The links are kept in the index file
+	tmpfile, err := os.CreateTemp("", "test_postal_code_finder_*.gob")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.Remove(tmpfile.Name())
+	}()
+	require.NoError(t, finder.SerializeIndex(tmpfile.Name()))
+	deserializedFinder, err := DeserializeIndex(tmpfile.Name(), IndexSettings{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"AD300"}, deserializedFinder.PostalCodes(2))
+	assert.Equal(t, int64(3066), deserializedFinder.CityByPostalCode("AD300", "AD")[0].Population)
+}
@@ -0,0 +1,122 @@
+package postalCode
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// CodeSummary is a postal code with the names of the places it covers and their centroid
+type CodeSummary struct {
+	PostalCode  string // Spelling of the code in the dataset
+	CountryCode string
+	PlaceNames  []string
+	GeonameIDs  []int // Places the entries of the code are linked to
+	Latitude    float64
+	Longitude   float64
+}
+
+// SearchResult holds the postal codes found by a prefix or range search, in ascending order
+type SearchResult struct {
+	Codes     []CodeSummary
+	Truncated bool // The limit was reached before all matching codes were listed
+}
+
+// SearchPrefix returns the postal codes of a country starting with a prefix, such as "10*".
+// Without the trailing "*" a prefix that is itself a complete code of the country, such as the UK outward code
+// "SW1A" or the Dutch "1012", only matches that code and the full codes it begins, so "SW1" leaves out "SW1A".
+// A limit of zero means no limit.
+func (pcf *Finder) SearchPrefix(prefix, countryCode string, limit int) SearchResult {
+	pcf.ensureSorted()
+	pcf.mutex.RLock()
+	defer pcf.mutex.RUnlock()
+
+	explicit := strings.HasSuffix(prefix, "*")
+	compact := Compact(strings.TrimSuffix(prefix, "*"))
+	truncate := pcf.formats[countryCode].Truncate
+	_, complete := pcf.formats.format(compact, countryCode)
+	complete = complete && !explicit && truncate > 0
+
+	keys := pcf.Keys[countryCode]
+	result := SearchResult{Codes: make([]CodeSummary, 0)}
+	for i := sort.SearchStrings(keys, compact); i < len(keys) && strings.HasPrefix(keys[i], compact); i++ {
+		if complete && len(keys[i]) != len(compact) && len(keys[i]) != len(compact)+truncate {
+			continue
+		}
+		if !result.add(pcf.summary(countryCode, keys[i]), limit) {
+			break
+		}
+	}
+	return result
+}
+
+// SearchRange returns the postal codes of a country between two codes of the same length, both included,
+// such as "80000" and "80999". The bounds are normalized like lookups, so "1067" stands for "01067" in DE.
+// A limit of zero means no limit.
+func (pcf *Finder) SearchRange(from, to, countryCode string, limit int) (SearchResult, error) {
+	pcf.ensureSorted()
+	pcf.mutex.RLock()
+	defer pcf.mutex.RUnlock()
+
+	from, to = pcf.formats.compactBound(from, countryCode), pcf.formats.compactBound(to, countryCode)
+	if from == "" || to == "" {
+		return SearchResult{}, fmt.Errorf("range bounds are required")
+	}
+	if len(from) != len(to) {
+		return SearchResult{}, fmt.Errorf("range bounds must have the same length")
+	}
+	if from > to {
+		return SearchResult{}, fmt.Errorf("range start is after its end")
+	}
+
+	keys := pcf.Keys[countryCode]
+	result := SearchResult{Codes: make([]CodeSummary, 0)}
+	for i := sort.SearchStrings(keys, from); i < len(keys) && keys[i] <= to; i++ {
+		// Longer codes sort between the bounds too, e.g. ZIP+4 codes, but are not part of the range
+		if len(keys[i]) != len(from) {
+			continue
+		}
+		if !result.add(pcf.summary(countryCode, keys[i]), limit) {
+			break
+		}
+	}
+	return result, nil
+}
+
+// add appends a code unless the limit is reached, in which case it marks the result as truncated
+func (r *SearchResult) add(summary CodeSummary, limit int) bool {
+	if limit > 0 && len(r.Codes) == limit {
+		r.Truncated = true
+		return false
+	}
+	r.Codes = append(r.Codes, summary)
+	return true
+}
+
+// summary lists the places of a postal code and averages their coordinates
+func (pcf *Finder) summary(countryCode, key string) CodeSummary {
+	entries := pcf.PostalCode[countryCode][key]
+	summary := CodeSummary{PostalCode: entries[0].PostalCode, CountryCode: countryCode}
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		summary.Latitude += entry.Latitude / float64(len(entries))
+		summary.Longitude += entry.Longitude / float64(len(entries))
+		if !seen[entry.PlaceName] {
+			seen[entry.PlaceName] = true
+			summary.PlaceNames = append(summary.PlaceNames, entry.PlaceName)
+		}
+		if entry.GeonameID != 0 && !slices.Contains(summary.GeonameIDs, entry.GeonameID) {
+			summary.GeonameIDs = append(summary.GeonameIDs, entry.GeonameID)
+		}
+	}
+	return summary
+}
+
+// compactBound returns the compact form of a range bound, in its canonical spelling when it is well-formed
+func (formats Formats) compactBound(bound, countryCode string) string {
+	if normalized, ok := formats.Normalize(bound, countryCode); ok {
+		return Compact(normalized)
+	}
+	return Compact(bound)
+}
@@ -0,0 +1,194 @@
+package postalCode
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/SamyRai/cityFinder/lib/dataLoader"
+	"github.com/cheggaaa/pb/v3"
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+)
+
+const earthRadiusKm = 6371.0
+
+// SpatialIndexVersion is the format version of the serialized spatial index. Bump it whenever the layout of the index
+// or of its entries changes, so that index files written before are rebuilt instead of misread.
+const SpatialIndexVersion = 1
+
+// SpatialIndex is an S2 index over the postal code centroids, for reverse lookups by coordinates
+type SpatialIndex struct {
+	Index   *s2.ShapeIndex
+	Entries []dataLoader.PostalCodeEntry // Entry of every indexed point, by edge ID
+}
+
+// SerializableSpatialIndex is a helper struct for gob encoding/decoding, the shape index is rebuilt on load
+type SerializableSpatialIndex struct {
+	Entries []dataLoader.PostalCodeEntry
+}
+
+// NearbyPlace is a place of a postal code together with its distance to a point
+type NearbyPlace struct {
+	Place
+	DistanceKm float64
+}
+
+// BuildSpatialIndex creates an S2 index over all entries of a postal code finder
+func BuildSpatialIndex(pcf *Finder) *SpatialIndex {
+	pcf.ensureSorted()
+	pcf.mutex.RLock()
+	defer pcf.mutex.RUnlock()
+
+	countries := make([]string, 0, len(pcf.Keys))
+	total := 0
+	for countryCode, entries := range pcf.PostalCode {
+		countries = append(countries, countryCode)
+		for _, codeEntries := range entries {
+			total += len(codeEntries)
+		}
+	}
+	sort.Strings(countries)
+
+	entries := make([]dataLoader.PostalCodeEntry, 0, total)
+	bar := pb.Full.Start(total)
+	for _, countryCode := range countries {
+		for _, key := range pcf.Keys[countryCode] {
+			entries = append(entries, pcf.PostalCode[countryCode][key]...)
+			bar.Add(len(pcf.PostalCode[countryCode][key]))
+		}
+	}
+	bar.Finish()
+
+	return newSpatialIndex(entries)
+}
+
+func newSpatialIndex(entries []dataLoader.PostalCodeEntry) *SpatialIndex {
+	points := make(s2.PointVector, len(entries))
+	for i, entry := range entries {
+		points[i] = s2.PointFromLatLng(s2.LatLngFromDegrees(entry.Latitude, entry.Longitude))
+	}
+	index := s2.NewShapeIndex()
+	index.Add(&points)
+	return &SpatialIndex{Index: index, Entries: entries}
+}
+
+// Nearest returns up to limit postal code places closest to a point, nearest first.
+// A positive radius drops the places further away than it, a limit of zero returns every place within the radius.
+// Places less accurate than minAccuracy are skipped, a minAccuracy of zero keeps them all.
+func (si *SpatialIndex) Nearest(lat, lon float64, limit int, radiusKm float64, minAccuracy int) ([]NearbyPlace, error) {
+	if si == nil || si.Index == nil {
+		return nil, fmt.Errorf("postal code spatial index is not initialized")
+	}
+	if limit <= 0 && radiusKm <= 0 {
+		return nil, fmt.Errorf("either a limit or a radius is required")
+	}
+
+	target := s2.NewMinDistanceToPointTarget(s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lon)))
+	// The query cannot filter by accuracy, so it asks for more places until enough of them are accurate
+	for fetch := limit; ; fetch *= 4 {
+		options := s2.NewClosestEdgeQueryOptions()
+		if fetch > 0 {
+			options = options.MaxResults(fetch)
+		}
+		if radiusKm > 0 {
+			// The limit is exclusive, its successor includes places right on the radius
+			options = options.DistanceLimit(s1.ChordAngleFromAngle(s1.Angle(radiusKm / earthRadiusKm)).Successor())
+		}
+
+		results := s2.NewClosestEdgeQuery(si.Index, options).FindEdges(target)
+		places := make([]NearbyPlace, 0, len(results))
+		for _, result := range results {
+			if int(result.EdgeID()) >= len(si.Entries) {
+				return nil, fmt.Errorf("invalid postal code index %d found (total entries: %d)", result.EdgeID(), len(si.Entries))
+			}
+			entry := si.Entries[result.EdgeID()]
+			if entry.Accuracy < minAccuracy {
+				continue
+			}
+			places = append(places, NearbyPlace{
+				Place:      NewPlace(entry),
+				DistanceKm: result.Distance().Angle().Radians() * earthRadiusKm,
+			})
+			if limit > 0 && len(places) == limit {
+				break
+			}
+		}
+		if fetch <= 0 || len(places) == limit || len(results) < fetch {
+			return places, nil
+		}
+	}
+}
+
+// Around returns every postal code with a place within the radius of a point, nearest first.
+// A code covering several places is listed once, with its closest place.
+func (si *SpatialIndex) Around(lat, lon, radiusKm float64, minAccuracy int) ([]NearbyPlace, error) {
+	if radiusKm <= 0 {
+		return nil, fmt.Errorf("radius must be positive")
+	}
+	places, err := si.Nearest(lat, lon, 0, radiusKm, minAccuracy)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[[2]string]bool, len(places))
+	codes := places[:0]
+	for _, place := range places {
+		key := [2]string{place.Country, Compact(place.PostalCode)}
+		if !seen[key] {
+			seen[key] = true
+			codes = append(codes, place)
+		}
+	}
+	return codes, nil
+}
+
+// SerializeIndex saves the entries of the spatial index to a file
+func (si *SpatialIndex) SerializeIndex(filepath string) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return fmt.Errorf("failed to create index file: %w", err)
+	}
+
+	encoder := gob.NewEncoder(file)
+	encodeErr := encoder.Encode(SpatialIndexVersion)
+	if encodeErr == nil {
+		encodeErr = encoder.Encode(SerializableSpatialIndex{Entries: si.Entries})
+	}
+	closeErr := file.Close()
+
+	if encodeErr != nil {
+		return encodeErr
+	}
+	return closeErr
+}
+
+// DeserializeSpatialIndex loads the entries of a spatial index from a file and rebuilds the S2 index over them.
+// Files of another format version are rejected.
+func DeserializeSpatialIndex(filepath string) (*SpatialIndex, error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file: %w", err)
+	}
+
+	var version int
+	var serializable SerializableSpatialIndex
+	decoder := gob.NewDecoder(file)
+	decodeErr := decoder.Decode(&version)
+	if decodeErr == nil && version != SpatialIndexVersion {
+		decodeErr = fmt.Errorf("index format version %d, expected %d", version, SpatialIndexVersion)
+	} else if decodeErr == nil {
+		decodeErr = decoder.Decode(&serializable)
+	}
+	closeErr := file.Close()
+
+	if decodeErr != nil {
+		return nil, fmt.Errorf("error decoding file: %w", decodeErr)
+	}
+	if closeErr != nil {
+		return nil, fmt.Errorf("error closing file: %w", closeErr)
+	}
+
+	return newSpatialIndex(serializable.Entries), nil
+}
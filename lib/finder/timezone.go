@@ -0,0 +1,57 @@
+package finder
+
+import (
+	"errors"
+	"time"
+
+	"github.com/SamyRai/cityFinder/lib/finder/coordinates"
+	"github.com/SamyRai/cityFinder/lib/finder/timezone"
+)
+
+// Sources of a time zone
+const (
+	TimezoneSourceBoundary = "boundary"
+	TimezoneSourcePlace    = "place"
+)
+
+// ErrUnknownTimezone is returned when no time zone is known for a point
+var ErrUnknownTimezone = errors.New("unknown time zone")
+
+// TimezoneMatch is the time zone of a point together with its local time
+type TimezoneMatch struct {
+	timezone.Time
+	Source    string // TimezoneSourceBoundary or TimezoneSourcePlace
+	GeonameID int    // Place the zone was taken from, 0 for boundaries
+}
+
+// FindTimezone returns the time zone of a point and the local time there at an instant. The zone boundary
+// polygons decide if they are loaded and contain the point, otherwise the zone of the nearest place is taken.
+// ErrUnknownTimezone is returned when neither gives a zone.
+func (f *Finder) FindTimezone(lat, lon float64, instant time.Time) (TimezoneMatch, error) {
+	match := TimezoneMatch{Source: TimezoneSourceBoundary}
+	zone, ok := "", false
+	if f.TimezoneFinder != nil {
+		zone, ok = f.TimezoneFinder.Zone(lat, lon)
+	}
+	if !ok {
+		place, _, err := f.FindNearestCity(lat, lon)
+		if errors.Is(err, coordinates.ErrNoPlace) {
+			return match, ErrUnknownTimezone
+		}
+		if err != nil {
+			return match, err
+		}
+		if place.Timezone == "" {
+			return match, ErrUnknownTimezone
+		}
+		zone = place.Timezone
+		match.Source, match.GeonameID = TimezoneSourcePlace, place.GeonameID
+	}
+
+	local, err := timezone.At(zone, instant)
+	if err != nil {
+		return match, err
+	}
+	match.Time = local
+	return match, nil
+}
@@ -0,0 +1,121 @@
+package timezone
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	_ "time/tzdata" // Embedded zone database, so zones resolve on hosts without one
+
+	"github.com/SamyRai/cityFinder/lib/dataLoader"
+	"github.com/golang/geo/s2"
+)
+
+// Time is the local time in a time zone at a given instant
+type Time struct {
+	Zone          string // IANA zone ID, e.g. Europe/Berlin
+	Abbreviation  string // Zone abbreviation in effect, e.g. CEST
+	UTCOffset     string // Offset from UTC in effect, e.g. +02:00
+	OffsetSeconds int
+	DST           bool // Whether daylight saving time is in effect
+	LocalTime     time.Time
+}
+
+// At returns the local time in a zone at an instant
+func At(zone string, instant time.Time) (Time, error) {
+	location, err := time.LoadLocation(zone)
+	if err != nil {
+		return Time{}, fmt.Errorf("failed to load time zone %s: %v", zone, err)
+	}
+
+	local := instant.In(location).Truncate(time.Second)
+	abbreviation, offset := local.Zone()
+	return Time{
+		Zone:          zone,
+		Abbreviation:  abbreviation,
+		UTCOffset:     local.Format("-07:00"),
+		OffsetSeconds: offset,
+		DST:           local.IsDST(),
+		LocalTime:     local,
+	}, nil
+}
+
+// Finder looks up the time zone of a point in the zone boundary polygons
+type Finder struct {
+	index *s2.ShapeIndex
+	zones map[s2.Shape]string // Map of zone polygon to IANA zone ID
+	mutex sync.RWMutex        // Mutex for thread-safe operations
+}
+
+// NewTimezoneFinder creates a new Finder instance
+func NewTimezoneFinder() *Finder {
+	return &Finder{
+		index: s2.NewShapeIndex(),
+		zones: make(map[s2.Shape]string),
+	}
+}
+
+// BuildIndex creates a time zone index from the zone boundary polygons
+func BuildIndex(boundaries []dataLoader.TimezoneBoundary) *Finder {
+	finder := NewTimezoneFinder()
+	for _, boundary := range boundaries {
+		finder.AddBoundary(boundary)
+	}
+	return finder
+}
+
+// AddBoundary adds the polygons of a zone to the Finder. Rings may run either way round, as each is normalized
+// to enclose the smaller area, and rings nested in another one are holes.
+func (tf *Finder) AddBoundary(boundary dataLoader.TimezoneBoundary) {
+	tf.mutex.Lock()
+	defer tf.mutex.Unlock()
+
+	for _, rings := range boundary.Polygons {
+		loops := make([]*s2.Loop, 0, len(rings))
+		for _, ring := range rings {
+			// GeoJSON rings repeat their first point at the end
+			if len(ring) > 1 && ring[0] == ring[len(ring)-1] {
+				ring = ring[:len(ring)-1]
+			}
+			if len(ring) < 3 {
+				continue
+			}
+			points := make([]s2.Point, len(ring))
+			for i, point := range ring {
+				points[i] = s2.PointFromLatLng(s2.LatLngFromDegrees(point[1], point[0]))
+			}
+			loop := s2.LoopFromPoints(points)
+			loop.Normalize()
+			loops = append(loops, loop)
+		}
+		if len(loops) == 0 {
+			continue
+		}
+		polygon := s2.PolygonFromLoops(loops)
+		tf.index.Add(polygon)
+		tf.zones[polygon] = boundary.TZID
+	}
+}
+
+// Zone returns the IANA zone ID of the boundary containing a point
+func (tf *Finder) Zone(lat, lon float64) (string, bool) {
+	tf.mutex.RLock()
+	defer tf.mutex.RUnlock()
+
+	if len(tf.zones) == 0 {
+		return "", false
+	}
+	query := s2.NewContainsPointQuery(tf.index, s2.VertexModelSemiOpen)
+	shapes := query.ContainingShapes(s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lon)))
+	if len(shapes) == 0 {
+		return "", false
+	}
+	return tf.zones[shapes[0]], true
+}
+
+// Len returns the number of zone polygons
+func (tf *Finder) Len() int {
+	tf.mutex.RLock()
+	defer tf.mutex.RUnlock()
+
+	return len(tf.zones)
+}
@@ -0,0 +1,59 @@
+package timezone
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SamyRai/cityFinder/lib/dataLoader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFinder_Zone(t *testing.T) {
+	finder := BuildIndex([]dataLoader.TimezoneBoundary{
+		// Clockwise shell with a hole, closed the GeoJSON way
+		{TZID: "Europe/Andorra", Polygons: [][][][2]float64{{
+			{{1.4, 42.4}, {1.4, 42.7}, {1.8, 42.7}, {1.8, 42.4}, {1.4, 42.4}},
+			{{1.5, 42.5}, {1.6, 42.5}, {1.6, 42.6}, {1.5, 42.6}},
+		}}},
+		{TZID: "Atlantic/Madeira", Polygons: [][][][2]float64{
+			{{{-17.3, 32.6}, {-16.6, 32.6}, {-16.6, 32.9}, {-17.3, 32.9}}},
+			{{{-16.4, 33.0}, {-16.3, 33.0}, {-16.3, 33.1}, {-16.4, 33.1}}},
+		}},
+	})
+	assert.Equal(t, 3, finder.Len())
+
+	zone, ok := finder.Zone(42.45, 1.7)
+	assert.True(t, ok)
+	assert.Equal(t, "Europe/Andorra", zone)
+
+	zone, ok = finder.Zone(33.05, -16.35)
+	assert.True(t, ok)
+	assert.Equal(t, "Atlantic/Madeira", zone)
+
+	_, ok = finder.Zone(42.55, 1.55)
+	assert.False(t, ok, "points in a hole are outside the zone")
+	_, ok = finder.Zone(48.85, 2.35)
+	assert.False(t, ok)
+	_, ok = NewTimezoneFinder().Zone(42.45, 1.7)
+	assert.False(t, ok)
+}
+
+func TestAt(t *testing.T) {
+	summer, err := At("Europe/Andorra", time.Date(2026, 7, 1, 12, 0, 0, 500, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, "Europe/Andorra", summer.Zone)
+	assert.Equal(t, "CEST", summer.Abbreviation)
+	assert.Equal(t, "+02:00", summer.UTCOffset)
+	assert.Equal(t, 7200, summer.OffsetSeconds)
+	assert.True(t, summer.DST)
+	assert.Equal(t, "2026-07-01T14:00:00+02:00", summer.LocalTime.Format(time.RFC3339Nano))
+
+	winter, err := At("America/St_Johns", time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, "-03:30", winter.UTCOffset)
+	assert.False(t, winter.DST)
+
+	_, err = At("Mars/Olympus_Mons", time.Now())
+	assert.Error(t, err)
+}
@@ -0,0 +1,22 @@
+package finder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SamyRai/cityFinder/lib/city"
+	"github.com/SamyRai/cityFinder/lib/config"
+	"github.com/SamyRai/cityFinder/lib/finder/coordinates"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFinder_FindTimezoneWithoutPlaces(t *testing.T) {
+	s2Finder, err := coordinates.BuildIndex([]city.SpatialCity{}, &config.S2{})
+	require.NoError(t, err)
+
+	// Without boundaries and places there is nothing to take the zone from
+	f := &Finder{S2Finder: s2Finder}
+	_, err = f.FindTimezone(42.55623, 1.53319, time.Now())
+	assert.ErrorIs(t, err, ErrUnknownTimezone)
+}
@@ -0,0 +1,127 @@
+package finder
+
+import (
+	"github.com/SamyRai/cityFinder/lib/city"
+)
+
+// PlaceByID returns the full record of a place with its divisions and postal codes
+func (f *Finder) PlaceByID(geonameID int) (Place, error) {
+	c, ok := f.S2Finder.PlaceByID(geonameID)
+	if !ok {
+		return Place{}, ErrUnknownPlace
+	}
+	return f.Describe(c), nil
+}
+
+// Ancestors returns the places containing a place, nearest first, e.g. its county, state, country and continent.
+// The hierarchy table is followed where it lists the place; places it leaves out, which includes most populated
+// places, are walked up through their admin codes until a division the table knows is reached.
+// Ancestors missing from the place index are skipped.
+func (f *Finder) Ancestors(geonameID int) ([]Place, error) {
+	c, ok := f.S2Finder.PlaceByID(geonameID)
+	if !ok {
+		return nil, ErrUnknownPlace
+	}
+
+	chain := f.adminChain(c)
+	var ids []int
+	for current := geonameID; ; {
+		if f.HierarchyFinder != nil {
+			if up := f.HierarchyFinder.Ancestors(current); len(up) > 0 {
+				ids = append(ids, up...)
+				break
+			}
+		}
+		next, ok := nextInChain(chain, current)
+		if !ok {
+			break
+		}
+		ids = append(ids, next)
+		current = next
+	}
+	return f.places(ids), nil
+}
+
+// Children returns the places directly below a place: those the hierarchy table lists under it, in file order,
+// followed by the places the table leaves out whose admin codes lead to it, in the order of the place index.
+// The latter are the places Ancestors walks up to it through their admin codes.
+func (f *Finder) Children(geonameID int) ([]Place, error) {
+	if _, ok := f.S2Finder.PlaceByID(geonameID); !ok {
+		return nil, ErrUnknownPlace
+	}
+
+	var ids []int
+	if f.HierarchyFinder != nil {
+		ids = f.HierarchyFinder.ChildIDs(geonameID)
+	}
+	f.adminChildrenOnce.Do(f.buildAdminChildren)
+	return f.places(append(ids, f.adminChildren[geonameID]...)), nil
+}
+
+// buildAdminChildren maps every division to the places below it through their admin codes, skipping the places
+// the hierarchy table lists with a parent
+func (f *Finder) buildAdminChildren() {
+	f.adminChildren = make(map[int][]int)
+	for i := range f.S2Finder.Cities {
+		c := &f.S2Finder.Cities[i]
+		if f.HierarchyFinder != nil && len(f.HierarchyFinder.Ancestors(c.GeonameID)) > 0 {
+			continue
+		}
+		if parent, ok := nextInChain(f.adminChain(c), c.GeonameID); ok {
+			f.adminChildren[parent] = append(f.adminChildren[parent], c.GeonameID)
+		}
+	}
+}
+
+// adminChain returns the geonameids of the second-order division, the first-order division and the country of a
+// place, bottom up, leaving out the unknown ones and the place itself
+func (f *Finder) adminChain(c *city.City) []int {
+	var chain []int
+	region, subregion := f.divisions(c)
+	if subregion != nil {
+		chain = append(chain, subregion.GeonameID)
+	}
+	if region != nil {
+		chain = append(chain, region.GeonameID)
+	}
+	if f.CountryFinder != nil {
+		if country, ok := f.CountryFinder.Country(c.Country); ok {
+			chain = append(chain, country.GeonameID)
+		}
+	}
+
+	filtered := chain[:0]
+	for _, id := range chain {
+		if id != 0 && id != c.GeonameID && (len(filtered) == 0 || filtered[len(filtered)-1] != id) {
+			filtered = append(filtered, id)
+		}
+	}
+	return filtered
+}
+
+// nextInChain returns the division above the current one, or the lowest one if the current place is not a division
+func nextInChain(chain []int, current int) (int, bool) {
+	for i, id := range chain {
+		if id == current {
+			if i+1 < len(chain) {
+				return chain[i+1], true
+			}
+			return 0, false
+		}
+	}
+	if len(chain) == 0 {
+		return 0, false
+	}
+	return chain[0], true
+}
+
+// places describes the places with the given geonameids, skipping those missing from the place index
+func (f *Finder) places(ids []int) []Place {
+	places := make([]Place, 0, len(ids))
+	for _, id := range ids {
+		if c, ok := f.S2Finder.PlaceByID(id); ok {
+			places = append(places, f.Describe(c))
+		}
+	}
+	return places
+}
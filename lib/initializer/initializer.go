@@ -7,9 +7,13 @@ import (
 	"github.com/SamyRai/cityFinder/lib/config"
 	"github.com/SamyRai/cityFinder/lib/dataLoader"
 	"github.com/SamyRai/cityFinder/lib/finder"
+	"github.com/SamyRai/cityFinder/lib/finder/admin"
 	"github.com/SamyRai/cityFinder/lib/finder/coordinates"
+	"github.com/SamyRai/cityFinder/lib/finder/country"
+	"github.com/SamyRai/cityFinder/lib/finder/hierarchy"
 	"github.com/SamyRai/cityFinder/lib/finder/name"
 	"github.com/SamyRai/cityFinder/lib/finder/postalCode"
+	"github.com/SamyRai/cityFinder/lib/finder/timezone"
 	"io"
 	"log"
 	"net/http"
@@ -42,6 +46,44 @@ func ensureDatasets(cfg *config.Config) error {
 	if err := downloadAndExtractDataset(cfg.PostalCodesURL, cfg.PostalCodesZip, cfg.PostalCodesFile, cfg); err != nil {
 		return err
 	}
+	for _, dataset := range cfg.PostalDatasets {
+		if err := downloadAndExtractDataset(dataset.URL, dataset.Zip, dataset.File, cfg); err != nil {
+			return err
+		}
+	}
+	if err := downloadDataset(cfg.Admin1CodesURL, cfg.Admin1CodesFile, cfg); err != nil {
+		return err
+	}
+	if err := downloadDataset(cfg.Admin2CodesURL, cfg.Admin2CodesFile, cfg); err != nil {
+		return err
+	}
+	if err := downloadDataset(cfg.CountryInfoURL, cfg.CountryInfoFile, cfg); err != nil {
+		return err
+	}
+	if err := downloadAndExtractDataset(cfg.HierarchyURL, cfg.HierarchyZip, cfg.HierarchyFile, cfg); err != nil {
+		return err
+	}
+	if err := downloadAndExtractDataset(cfg.AlternateNamesURL, cfg.AlternateNamesZip, cfg.AlternateNamesFile, cfg); err != nil {
+		return err
+	}
+	return nil
+}
+
+// downloadDataset downloads an uncompressed dataset if not already present
+func downloadDataset(url, fileName string, cfg *config.Config) error {
+	if url == "" || fileName == "" {
+		return nil
+	}
+	filePath := filepath.Join(cfg.DatasetsFolder, fileName)
+
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		log.Printf("Downloading %s...", url)
+		if err := downloadFile(filePath, url); err != nil {
+			return fmt.Errorf("failed to download %s: %v", url, err)
+		}
+	} else {
+		log.Printf("Dataset file %s already exists, skipping download.", filePath)
+	}
 	return nil
 }
 
@@ -103,14 +145,23 @@ func downloadFile(filepath string, url string) error {
 	return outCloseErr
 }
 
-// unzipAndRename unzips a file and renames it to the specified new file name
+// unzipAndRename unzips a file and renames it to the specified new file name.
+// When the archive holds several files and one of them already has the new file name, only that one is extracted.
 func unzipAndRename(src string, dest string, newFileName string) error {
 	r, err := zip.OpenReader(src)
 	if err != nil {
 		return err
 	}
 
+	files := r.File
 	for _, f := range r.File {
+		if filepath.Base(f.Name) == newFileName {
+			files = []*zip.File{f}
+			break
+		}
+	}
+
+	for _, f := range files {
 		fpath := filepath.Join(dest, f.Name)
 		if !strings.HasPrefix(fpath, filepath.Clean(dest)+string(os.PathSeparator)) {
 			return fmt.Errorf("%s: illegal file path", fpath)
@@ -163,19 +214,115 @@ func ensureFinders(cfg *config.Config) (*finder.Finder, error) {
 		return nil, err
 	}
 
-	postalCodeFinder, err := ensurePostalCodeIndex(cfg, postalCodes)
+	postalCodeFinder, postalCodesRebuilt, err := ensurePostalCodeIndex(cfg, postalCodes, cities)
+	if err != nil {
+		return nil, err
+	}
+
+	postalCodeSpatialIndex, err := ensurePostalCodeSpatialIndex(cfg, postalCodeFinder, postalCodesRebuilt)
 	if err != nil {
 		return nil, err
 	}
 
+	adminFinder, err := loadAdminFinder(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	countryFinder, err := loadCountryFinder(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	hierarchyFinder, err := loadHierarchyFinder(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	timezoneFinder, err := loadTimezoneFinder(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	postalCodeFinder.SetFormats(postalCodeFormats(countryFinder))
+
 	return &finder.Finder{
-		S2Finder:         s2Finder,
-		NameFinder:       nameFinder,
-		PostalCodeFinder: postalCodeFinder,
+		S2Finder:               s2Finder,
+		NameFinder:             nameFinder,
+		PostalCodeFinder:       postalCodeFinder,
+		PostalCodeSpatialIndex: postalCodeSpatialIndex,
+		AdminFinder:            adminFinder,
+		CountryFinder:          countryFinder,
+		HierarchyFinder:        hierarchyFinder,
+		TimezoneFinder:         timezoneFinder,
 	}, nil
 }
 
-func loadData(cfg *config.Config) ([]city.SpatialCity, map[string]map[string]dataLoader.PostalCodeEntry, error) {
+// loadAdminFinder loads the admin code tables, which are small enough to be read on every start
+func loadAdminFinder(cfg *config.Config) (*admin.Finder, error) {
+	var admin1Codes, admin2Codes []dataLoader.AdminCode
+	var err error
+	if cfg.Admin1CodesFile != "" {
+		admin1Codes, err = dataLoader.LoadAdminCodes(filepath.Join(cfg.DatasetsFolder, cfg.Admin1CodesFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load admin1 codes: %v", err)
+		}
+	}
+	if cfg.Admin2CodesFile != "" {
+		admin2Codes, err = dataLoader.LoadAdminCodes(filepath.Join(cfg.DatasetsFolder, cfg.Admin2CodesFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load admin2 codes: %v", err)
+		}
+	}
+	return admin.BuildIndex(admin1Codes, admin2Codes), nil
+}
+
+// loadCountryFinder loads the country table, which is small enough to be read on every start
+func loadCountryFinder(cfg *config.Config) (*country.Finder, error) {
+	if cfg.CountryInfoFile == "" {
+		return country.NewCountryFinder(), nil
+	}
+	countries, err := dataLoader.LoadCountryInfo(filepath.Join(cfg.DatasetsFolder, cfg.CountryInfoFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load country info: %v", err)
+	}
+	return country.BuildIndex(countries), nil
+}
+
+// postalCodeFormats derives the postal code formats from the layouts listed in the country table
+func postalCodeFormats(countryFinder *country.Finder) postalCode.Formats {
+	layouts := make(map[string]string)
+	for _, c := range countryFinder.List("") {
+		layouts[c.ISO] = c.PostalCodeFormat
+	}
+	return postalCode.NewFormats(layouts)
+}
+
+// loadHierarchyFinder loads the parent-child table of places, which is small enough to be read on every start
+func loadHierarchyFinder(cfg *config.Config) (*hierarchy.Finder, error) {
+	if cfg.HierarchyFile == "" {
+		return hierarchy.NewHierarchyFinder(), nil
+	}
+	links, err := dataLoader.LoadHierarchy(filepath.Join(cfg.DatasetsFolder, cfg.HierarchyFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load hierarchy: %v", err)
+	}
+	return hierarchy.BuildIndex(links), nil
+}
+
+// loadTimezoneFinder loads the optional time zone boundary polygons. Without them time zones are taken from the nearest place.
+func loadTimezoneFinder(cfg *config.Config) (*timezone.Finder, error) {
+	if cfg.TimezoneBoundaryFile == "" {
+		return nil, nil
+	}
+	boundaries, err := dataLoader.LoadTimezoneBoundaries(filepath.Join(cfg.DatasetsFolder, cfg.TimezoneBoundaryFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load time zone boundaries: %v", err)
+	}
+	return timezone.BuildIndex(boundaries), nil
+}
+
+func loadData(cfg *config.Config) ([]city.SpatialCity, map[string]map[string][]dataLoader.PostalCodeEntry, error) {
 	cities, err := dataLoader.LoadGeoNamesCSV(filepath.Join(cfg.DatasetsFolder, cfg.AllCitiesFile))
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to load GeoNames data from CSV: %v", err)
@@ -185,6 +332,14 @@ func loadData(cfg *config.Config) ([]city.SpatialCity, map[string]map[string]dat
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to load Postal Code data: %v", err)
 	}
+	for _, dataset := range cfg.PostalDatasets {
+		log.Printf("Merging postal code dataset %s", dataset.File)
+		datasetCodes, err := dataLoader.LoadPostalCodes(filepath.Join(cfg.DatasetsFolder, dataset.File))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load postal code dataset %s: %v", dataset.File, err)
+		}
+		dataLoader.MergePostalCodes(postalCodes, datasetCodes, dataset.Replace)
+	}
 
 	return cities, postalCodes, nil
 }
@@ -197,19 +352,22 @@ func ensureS2Index(cfg *config.Config, cities []city.SpatialCity) (*coordinates.
 	log.Printf("Ensuring S2 index is built and serialized in %s", s2IndexPath)
 	if _, errStat := os.Stat(s2IndexPath); os.IsNotExist(errStat) {
 		log.Printf("S2 index not found in %s\nBuilding it...", s2IndexPath)
-		s2Finder, err = coordinates.BuildIndex(cities, &cfg.S2)
-		if err != nil {
-			return nil, fmt.Errorf("failed to build S2 index: %v", err)
-		}
-		err = s2Finder.SerializeIndex(s2IndexPath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to serialize S2 index: %v", err)
-		}
 	} else {
 		s2Finder, err = coordinates.DeserializeIndex(s2IndexPath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to deserialize S2 index: %v", err)
+		if err == nil {
+			return s2Finder, nil
 		}
+		// Indexes written by an older version, or damaged ones, are rebuilt from the datasets
+		log.Printf("Failed to deserialize S2 index in %s: %v\nRebuilding it...", s2IndexPath, err)
+	}
+
+	s2Finder, err = coordinates.BuildIndex(cities, &cfg.S2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build S2 index: %v", err)
+	}
+	err = s2Finder.SerializeIndex(s2IndexPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize S2 index: %v", err)
 	}
 	return s2Finder, nil
 }
@@ -222,38 +380,106 @@ func ensureNameIndex(cfg *config.Config, cities []city.SpatialCity) (*name.Finde
 	log.Printf("Ensuring name index is built and serialized in %s", nameIndexPath)
 	if _, errStat := os.Stat(nameIndexPath); os.IsNotExist(errStat) {
 		log.Printf("Name index not found in %s\nBuilding it...", nameIndexPath)
-		nameFinder = name.BuildIndex(cities)
-		err = nameFinder.SerializeIndex(nameIndexPath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to serialize name index: %v", err)
-		}
 	} else {
-		nameFinder, err = name.DeserializeIndex(nameIndexPath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to deserialize name index: %v", err)
+		nameFinder, err = name.DeserializeIndex(nameIndexPath, nameIndexSettings(cfg))
+		if err == nil {
+			return nameFinder, nil
 		}
+		// Indexes written by an older version, built with other settings, or damaged ones, are rebuilt from the datasets
+		log.Printf("Failed to deserialize name index in %s: %v\nRebuilding it...", nameIndexPath, err)
+	}
+
+	alternateNames, err := loadAlternateNames(cfg)
+	if err != nil {
+		return nil, err
+	}
+	nameFinder, err = name.BuildIndex(cities, alternateNames, nameIndexSettings(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build name index: %v", err)
+	}
+	err = nameFinder.SerializeIndex(nameIndexPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize name index: %v", err)
 	}
 	return nameFinder, nil
 }
 
-func ensurePostalCodeIndex(cfg *config.Config, postalCodes map[string]map[string]dataLoader.PostalCodeEntry) (*postalCode.Finder, error) {
+// nameIndexSettings returns the configuration options the name index is built with
+func nameIndexSettings(cfg *config.Config) name.IndexSettings {
+	return name.IndexSettings{FuzzyEngine: cfg.FuzzyEngine, AlternateNamesFile: cfg.AlternateNamesFile, Languages: cfg.Languages}
+}
+
+// loadAlternateNames loads the language-tagged alternate names, which are only needed to build the name index
+func loadAlternateNames(cfg *config.Config) ([]dataLoader.AlternateName, error) {
+	if cfg.AlternateNamesFile == "" {
+		return nil, nil
+	}
+	alternateNames, err := dataLoader.LoadAlternateNames(filepath.Join(cfg.DatasetsFolder, cfg.AlternateNamesFile), cfg.Languages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load alternate names: %v", err)
+	}
+	return alternateNames, nil
+}
+
+// ensurePostalCodeIndex loads the postal code index, building it if it does not exist or cannot be loaded,
+// and reports whether it was built
+func ensurePostalCodeIndex(cfg *config.Config, postalCodes map[string]map[string][]dataLoader.PostalCodeEntry, cities []city.SpatialCity) (*postalCode.Finder, bool, error) {
 	postalCodeIndexPath := filepath.Join(cfg.DatasetsFolder, cfg.PostalCodeIndexFile)
-	var postalCodeFinder *postalCode.Finder
-	var err error
 
 	log.Printf("Ensuring postal code index is built and serialized in %s", postalCodeIndexPath)
 	if _, errStat := os.Stat(postalCodeIndexPath); os.IsNotExist(errStat) {
 		log.Printf("Postal code index not found in %s\nBuilding it...", postalCodeIndexPath)
-		postalCodeFinder = postalCode.BuildIndex(postalCodes)
-		err = postalCodeFinder.SerializeIndex(postalCodeIndexPath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to serialize postal code index: %v", err)
+	} else {
+		postalCodeFinder, err := postalCode.DeserializeIndex(postalCodeIndexPath, postalCodeIndexSettings(cfg))
+		if err == nil {
+			return postalCodeFinder, false, nil
 		}
+		// Indexes written by an older version, built from other datasets, or damaged ones, are rebuilt from the datasets
+		log.Printf("Failed to deserialize postal code index in %s: %v\nRebuilding it...", postalCodeIndexPath, err)
+	}
+
+	log.Printf("Linking postal codes to places...")
+	postalCode.LinkPlaces(postalCodes, cities)
+	postalCodeFinder := postalCode.BuildIndex(postalCodes, postalCodeIndexSettings(cfg))
+	if err := postalCodeFinder.SerializeIndex(postalCodeIndexPath); err != nil {
+		return nil, false, fmt.Errorf("failed to serialize postal code index: %v", err)
+	}
+	return postalCodeFinder, true, nil
+}
+
+// postalCodeIndexSettings returns the postal code datasets the postal code index is built from
+func postalCodeIndexSettings(cfg *config.Config) postalCode.IndexSettings {
+	settings := postalCode.IndexSettings{PostalCodesFile: cfg.PostalCodesFile}
+	for _, dataset := range cfg.PostalDatasets {
+		settings.Datasets = append(settings.Datasets, postalCode.Dataset{File: dataset.File, Replace: dataset.Replace})
+	}
+	return settings
+}
+
+// ensurePostalCodeSpatialIndex loads the S2 index over the postal code centroids, building it from the
+// postal code index if it does not exist yet or the postal code index was just rebuilt.
+// It is optional and skipped when no file is configured.
+func ensurePostalCodeSpatialIndex(cfg *config.Config, postalCodeFinder *postalCode.Finder, rebuild bool) (*postalCode.SpatialIndex, error) {
+	if cfg.PostalCodeS2IndexFile == "" {
+		return nil, nil
+	}
+	spatialIndexPath := filepath.Join(cfg.DatasetsFolder, cfg.PostalCodeS2IndexFile)
+
+	log.Printf("Ensuring postal code S2 index is built and serialized in %s", spatialIndexPath)
+	if _, errStat := os.Stat(spatialIndexPath); os.IsNotExist(errStat) || rebuild {
+		log.Printf("Postal code S2 index not found in %s or outdated\nBuilding it...", spatialIndexPath)
 	} else {
-		postalCodeFinder, err = postalCode.DeserializeIndex(postalCodeIndexPath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to deserialize postal code index: %v", err)
+		spatialIndex, err := postalCode.DeserializeSpatialIndex(spatialIndexPath)
+		if err == nil {
+			return spatialIndex, nil
 		}
+		// Indexes written by an older version, or damaged ones, are rebuilt from the postal code index
+		log.Printf("Failed to deserialize postal code S2 index in %s: %v\nRebuilding it...", spatialIndexPath, err)
+	}
+
+	spatialIndex := postalCode.BuildSpatialIndex(postalCodeFinder)
+	if err := spatialIndex.SerializeIndex(spatialIndexPath); err != nil {
+		return nil, fmt.Errorf("failed to serialize postal code S2 index: %v", err)
 	}
-	return postalCodeFinder, nil
+	return spatialIndex, nil
 }
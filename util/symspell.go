@@ -0,0 +1,154 @@
+package util
+
+// FuzzyIndex finds the terms within a given edit distance of a query
+type FuzzyIndex interface {
+	Add(term string)
+	Search(query string, maxDistance int) []string
+}
+
+// SymSpell is a symmetric delete dictionary for fast fuzzy string matching.
+// Every term is indexed under all the strings obtained by deleting up to MaxDistance characters from its prefix,
+// so a lookup only needs the deletions of the query instead of a distance computation per visited node.
+type SymSpell struct {
+	MaxDistance  int
+	PrefixLength int
+	Terms        []string
+	Deletes      map[string][]int32 // Map of deletion variant to the indexes of the terms it was derived from
+	known        map[string]bool    // Set of the terms, rebuilt from Terms on first use after deserialization
+}
+
+// NewSymSpell creates a new SymSpell dictionary supporting searches up to maxDistance
+func NewSymSpell(maxDistance int) *SymSpell {
+	return &SymSpell{
+		MaxDistance:  maxDistance,
+		PrefixLength: 7,
+		Deletes:      make(map[string][]int32),
+	}
+}
+
+// Add inserts a term into the dictionary, terms that are already in it are skipped
+func (s *SymSpell) Add(term string) {
+	if s.known == nil {
+		s.known = make(map[string]bool, len(s.Terms))
+		for _, known := range s.Terms {
+			s.known[known] = true
+		}
+	}
+	if s.known[term] {
+		return
+	}
+	s.known[term] = true
+
+	index := int32(len(s.Terms))
+	s.Terms = append(s.Terms, term)
+	for _, variant := range s.deletes(term) {
+		s.Deletes[variant] = append(s.Deletes[variant], index)
+	}
+}
+
+// Search returns the terms within the given distance of the query term
+func (s *SymSpell) Search(query string, maxDistance int) []string {
+	if maxDistance > s.MaxDistance {
+		maxDistance = s.MaxDistance
+	}
+	seen := make(map[int32]bool)
+	var results []string
+	for _, variant := range s.deletes(query) {
+		for _, index := range s.Deletes[variant] {
+			if seen[index] {
+				continue
+			}
+			seen[index] = true
+			if _, within := LevenshteinWithin(query, s.Terms[index], maxDistance); within {
+				results = append(results, s.Terms[index])
+			}
+		}
+	}
+	return results
+}
+
+// deletes returns the prefix of a term and every variant of it with up to MaxDistance characters deleted
+func (s *SymSpell) deletes(term string) []string {
+	if len(term) > s.PrefixLength {
+		term = term[:s.PrefixLength]
+	}
+	variants := []string{term}
+	seen := map[string]bool{term: true}
+	for start, distance := 0, 0; distance < s.MaxDistance; distance++ {
+		end := len(variants)
+		for _, variant := range variants[start:end] {
+			for i := 0; i < len(variant); i++ {
+				deleted := variant[:i] + variant[i+1:]
+				if !seen[deleted] {
+					seen[deleted] = true
+					variants = append(variants, deleted)
+				}
+			}
+		}
+		start = end
+	}
+	return variants
+}
+
+// LevenshteinWithin calculates the Levenshtein distance between two strings if it does not exceed max.
+// Only the band of the matrix within max of the diagonal is computed, and the computation stops as soon
+// as a whole row exceeds max. Strings up to 63 bytes are handled without allocating.
+func LevenshteinWithin(a, b string, max int) (int, bool) {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(b)-len(a) > max {
+		return 0, false
+	}
+
+	var prevBuf, currBuf [64]int
+	prev, curr := prevBuf[:], currBuf[:]
+	if len(b) >= len(prevBuf) {
+		prev, curr = make([]int, len(b)+1), make([]int, len(b)+1)
+	}
+	for j := 0; j <= len(b); j++ {
+		prev[j] = j
+	}
+
+	outside := max + 1
+	for i := 1; i <= len(a); i++ {
+		from, to := i-max, i+max
+		if from < 1 {
+			from = 1
+		}
+		if to > len(b) {
+			to = len(b)
+		}
+		curr[0] = i
+		if from > 1 {
+			curr[from-1] = outside
+		}
+		rowMin := curr[0]
+		if from > 1 {
+			rowMin = outside
+		}
+		for j := from; j <= to; j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			value := min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			curr[j] = value
+			if value < rowMin {
+				rowMin = value
+			}
+		}
+		if to < len(b) {
+			curr[to+1] = outside
+		}
+		if rowMin > max {
+			return 0, false
+		}
+		prev, curr = curr, prev
+	}
+
+	if prev[len(b)] > max {
+		return 0, false
+	}
+	return prev[len(b)], true
+}
@@ -0,0 +1,150 @@
+package util
+
+import (
+	"bufio"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loadTerms reads the lower-cased names and alternate names of the test dataset
+func loadTerms(tb testing.TB) []string {
+	file, err := os.Open("../testdata/allCountries.txt")
+	require.NoError(tb, err)
+	defer func() {
+		_ = file.Close()
+	}()
+
+	seen := make(map[string]bool)
+	var terms []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 4 {
+			continue
+		}
+		for _, term := range append(strings.Split(fields[3], ","), fields[1]) {
+			term = strings.ToLower(term)
+			if term != "" && !seen[term] {
+				seen[term] = true
+				terms = append(terms, term)
+			}
+		}
+	}
+	require.NoError(tb, scanner.Err())
+	return terms
+}
+
+// misspell applies up to two random edits to a term
+func misspell(random *rand.Rand, term string) string {
+	letters := "abcdefghijklmnopqrstuvwxyz"
+	for edits := random.Intn(3); edits > 0 && len(term) > 1; edits-- {
+		i := random.Intn(len(term))
+		switch random.Intn(3) {
+		case 0:
+			term = term[:i] + term[i+1:]
+		case 1:
+			term = term[:i] + string(letters[random.Intn(len(letters))]) + term[i:]
+		default:
+			term = term[:i] + string(letters[random.Intn(len(letters))]) + term[i+1:]
+		}
+	}
+	return term
+}
+
+func TestLevenshteinWithin(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	terms := loadTerms(t)
+	for i := 0; i < 2000; i++ {
+		a := terms[random.Intn(len(terms))]
+		b := misspell(random, terms[random.Intn(len(terms))])
+		if i%2 == 0 {
+			b = misspell(random, a)
+		}
+		expected := LevenshteinDistance(a, b)
+		for max := 0; max <= 3; max++ {
+			distance, within := LevenshteinWithin(a, b, max)
+			assert.Equal(t, expected <= max, within, "%q %q %d", a, b, max)
+			if within {
+				assert.Equal(t, expected, distance, "%q %q %d", a, b, max)
+			}
+		}
+	}
+}
+
+func TestSymSpellMatchesBKTree(t *testing.T) {
+	terms := loadTerms(t)
+	bkTree := NewBKTree()
+	symSpell := NewSymSpell(2)
+	for _, term := range terms {
+		bkTree.Add(term)
+		symSpell.Add(term)
+	}
+
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		query := misspell(random, terms[random.Intn(len(terms))])
+		expected := bkTree.Search(query, 2)
+		actual := symSpell.Search(query, 2)
+		sort.Strings(expected)
+		sort.Strings(actual)
+		assert.Equal(t, expected, actual, query)
+	}
+}
+
+func TestSymSpellAddOnce(t *testing.T) {
+	symSpell := NewSymSpell(2)
+	symSpell.Add("berlin")
+	symSpell.Add("berlin")
+	symSpell.Add("bern")
+	symSpell.Add("berlin")
+
+	assert.Equal(t, []string{"berlin", "bern"}, symSpell.Terms)
+	assert.Len(t, symSpell.Deletes["berl"], 1, "variants of a term are indexed once")
+	assert.Equal(t, []string{"berlin"}, symSpell.Search("berlim", 1))
+}
+
+func benchmarkFuzzyIndex(b *testing.B, index FuzzyIndex) {
+	terms := loadTerms(b)
+	for _, term := range terms {
+		index.Add(term)
+	}
+	random := rand.New(rand.NewSource(1))
+	queries := make([]string, 1000)
+	for i := range queries {
+		queries[i] = misspell(random, terms[random.Intn(len(terms))])
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index.Search(queries[i%len(queries)], 2)
+	}
+}
+
+func BenchmarkBKTreeSearch(b *testing.B) {
+	benchmarkFuzzyIndex(b, NewBKTree())
+}
+
+func BenchmarkSymSpellSearch(b *testing.B) {
+	benchmarkFuzzyIndex(b, NewSymSpell(2))
+}
+
+func BenchmarkLevenshteinDistance(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		LevenshteinDistance("sant julia de loria", "san julian de loria")
+	}
+}
+
+func BenchmarkLevenshteinWithin(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		LevenshteinWithin("sant julia de loria", "san julian de loria", 2)
+	}
+}